@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"log"
 	"os"
@@ -11,31 +12,62 @@ import (
 	"testberry/internal/adapters/cache"
 	"testberry/internal/adapters/http"
 	messagebrok "testberry/internal/adapters/message_brok"
+	"testberry/internal/adapters/metrics"
 	"testberry/internal/adapters/postgres"
+	"testberry/internal/adapters/tracing"
+	order_entity "testberry/internal/domain/order"
 	"testberry/internal/domain/service"
+	"testberry/internal/ports"
 	"testberry/pkg/config"
 	"testberry/pkg/logger"
+	"testberry/pkg/redact"
+	"testberry/pkg/retry"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
-	logger := logger.NewSlogAdapter()
+	cfg := config.LoadConfig()
+	piiPolicies, err := redact.ParsePolicies(cfg.PII.Policies)
+	if err != nil {
+		log.Fatalf("invalid PII_POLICIES: %v", err)
+	}
+	redactor := redact.New(piiPolicies, []byte(cfg.PII.HashKey))
+	logger, err := logger.New(os.Stdout, cfg.Log.Format, cfg.Log.Level, logger.WithRedactor(redactor))
+	if err != nil {
+		log.Fatalf("could not set up logger: %v", err)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	logger.Info("[1/7] Reading configurations")
-	cfg := config.LoadConfig()
+	logger.Info("[1/7] Configuration loaded", "log_format", cfg.Log.Format, "log_level", cfg.Log.Level)
+	dbLogger := logger.With("component", "postgres")
+	cacheLogger := logger.With("component", "cache")
+	kafkaLogger := logger.With("component", "kafka")
 
 	connStr := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, cfg.DB.SSLMode,
 	)
 
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.ServiceName, cfg.Tracing.Endpoint)
+	if err != nil {
+		log.Fatalf("could not set up tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", "err", err)
+		}
+	}()
+
 	logger.Info("[2/7] Connecting to the DB")
 	db, err := postgres.ConnectDB(connStr)
 	if err != nil {
@@ -43,41 +75,160 @@ func main() {
 	}
 	defer func() {
 		if err := db.Close(); err != nil {
-			logger.Error("failed to close db", err)
+			logger.Error("failed to close db", "err", err)
 		}
 	}()
 
-	logger.Info("[3/7] Connecting to Redis")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, db, dbLogger, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if cfg.DB.MigrateOnStart {
+		logger.Info("Applying database migrations")
+		if err := migrateOnStart(ctx, db, dbLogger); err != nil {
+			log.Fatalf("could not migrate db: %v", err)
+		}
+	}
+
+	logger.Info("[3/7] Setting up the cache", "backend", cfg.Cache.Backend)
+	var (
+		cacheClient   ports.Cache
+		tieredCache   *cache.TieredCache
+		customerPages ports.CustomerPageCache
+		redisPing     func(ctx context.Context) error
+	)
 	redisAddr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
-	cacheClient := cache.NewCache(redisAddr, cfg.Redis.Password, cfg.Redis.DB)
-	repo := postgres.NewRepository(db, logger)
+	switch cfg.Cache.Backend {
+	case "redis":
+		redisCache := cache.NewCache(redisAddr, cfg.Redis.Password, cfg.Redis.DB, cache.WithTTL(cfg.Cache.TTL))
+		cacheClient = redisCache
+		customerPages = cache.NewCustomerPages(redisCache, cfg.Cache.PageTTL)
+		redisPing = redisCache.Ping
+	case "tiered":
+		localCache := cache.NewMemoryCache(
+			cache.WithMaxEntries(cfg.Cache.MaxEntries),
+			cache.WithMaxBytes(cfg.Cache.MaxBytes),
+			cache.WithMemoryTTL(cfg.Cache.LocalTTL),
+		)
+		expvar.Publish("order_cache", expvar.Func(func() interface{} { return localCache.Stats() }))
+		redisCache := cache.NewCache(redisAddr, cfg.Redis.Password, cfg.Redis.DB, cache.WithTTL(cfg.Cache.TTL))
+		tieredCache = cache.NewTieredCache(localCache, redisCache, cfg.Cache.Channel, cacheLogger)
+		cacheClient = tieredCache
+		customerPages = cache.NewCustomerPages(redisCache, cfg.Cache.PageTTL)
+		redisPing = redisCache.Ping
+	case "memory":
+		memoryCache := cache.NewMemoryCache(
+			cache.WithMaxEntries(cfg.Cache.MaxEntries),
+			cache.WithMaxBytes(cfg.Cache.MaxBytes),
+			cache.WithMemoryTTL(cfg.Cache.TTL),
+		)
+		expvar.Publish("order_cache", expvar.Func(func() interface{} { return memoryCache.Stats() }))
+		cacheClient = memoryCache
+	default:
+		log.Fatalf("invalid CACHE_BACKEND %q, expected redis, tiered or memory", cfg.Cache.Backend)
+	}
+	warmupMode, err := service.ParseWarmupMode(cfg.Cache.WarmupMode)
+	if err != nil {
+		log.Fatalf("invalid CACHE_WARMUP_MODE: %v", err)
+	}
+	conflictPolicy, err := postgres.ParseConflictPolicy(cfg.DB.ConflictPolicy)
+	if err != nil {
+		log.Fatalf("invalid ORDER_CONFLICT_POLICY: %v", err)
+	}
+	repo := postgres.NewRepository(db, dbLogger, postgres.WithConflictPolicy(conflictPolicy))
+	businessRules, err := order_entity.NewRuleValidator(cfg.DB.DisabledRules...)
+	if err != nil {
+		log.Fatalf("invalid ORDER_RULES_DISABLED: %v, known rules: %v", err, order_entity.RuleNames())
+	}
 
 	logger.Info("[4/7] Create Kafka Consumer")
 	kafkaBrokers := cfg.Kafka.Brokers
 	kafkaTopic := cfg.Kafka.Topic
 
-	consumer, err := messagebrok.NewConsumer(kafkaBrokers, "order-consumer-group", kafkaTopic)
+	deadLetterProducer, err := messagebrok.NewProducer(kafkaBrokers, cfg.Kafka.DeadLetterTopic, kafkaLogger)
+	if err != nil {
+		log.Fatalf("Failed to start Kafka dead-letter producer: %v", err)
+	}
+	defer func() {
+		if err := deadLetterProducer.Close(); err != nil {
+			logger.Error("failed to close kafka dead-letter producer", "err", err)
+		}
+	}()
+
+	consumer, err := messagebrok.NewConsumer(kafkaBrokers, "order-consumer-group", kafkaTopic, deadLetterProducer, kafkaLogger)
 	if err != nil {
 		log.Fatalf("Failed to create Kafka consumer: %v", err)
 	}
 	defer func() {
 		if err := consumer.Close(); err != nil {
-			logger.Error("failed to close kafka consumer", err)
+			logger.Error("failed to close kafka consumer", "err", err)
 		}
 	}()
 
 	logger.Info("[5/7] Create Kafka Producer")
-	producer, err := messagebrok.NewProducer(kafkaBrokers, kafkaTopic)
+	producer, err := messagebrok.NewProducer(kafkaBrokers, kafkaTopic, kafkaLogger)
 	if err != nil {
 		log.Fatalf("Failed to start Kafka producer: %v", err)
 	}
 	defer func() {
 		if err := producer.Close(); err != nil {
-			logger.Error("failed to close kafka producer", err)
+			logger.Error("failed to close kafka producer", "err", err)
+		}
+	}()
+
+	outboxProducer, err := messagebrok.NewProducer(kafkaBrokers, cfg.Kafka.OutboxTopic, kafkaLogger)
+	if err != nil {
+		log.Fatalf("Failed to start Kafka outbox producer: %v", err)
+	}
+	defer func() {
+		if err := outboxProducer.Close(); err != nil {
+			logger.Error("failed to close kafka outbox producer", "err", err)
 		}
 	}()
 
-	service := service.NewService(repo, cacheClient, consumer, producer, logger)
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxAttempts = cfg.Retry.MaxAttempts
+	retryPolicy.InitialInterval = cfg.Retry.InitialInterval
+	retryPolicy.MaxInterval = cfg.Retry.MaxInterval
+	retryPolicy.MaxElapsed = cfg.Retry.MaxElapsed
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	appMetrics := metrics.New(registry)
+
+	relay := service.NewOutboxRelay(repo, metrics.NewProducer(outboxProducer, "outbox", appMetrics), logger.With("component", "outbox"),
+		service.WithRelayInterval(cfg.Outbox.PollInterval),
+		service.WithRelayBatchSize(cfg.Outbox.BatchSize),
+		service.WithRelayRetention(cfg.Outbox.Retention),
+	)
+	warmupPolicy := service.WarmupPolicy{Mode: warmupMode, Limit: cfg.Cache.WarmupLimit, Since: cfg.Cache.WarmupSince}
+	if err := warmupPolicy.Validate(); err != nil {
+		log.Fatalf("invalid cache warm-up policy: %v", err)
+	}
+
+	service := service.NewService(
+		metrics.NewRepository(tracing.NewRepository(repo), appMetrics),
+		metrics.NewCache(tracing.NewCache(cacheClient), appMetrics),
+		metrics.NewConsumer(consumer, appMetrics),
+		metrics.NewProducer(producer, "generator", appMetrics),
+		logger.With("component", "service"),
+		service.WithRetryPolicy(retryPolicy),
+		service.WithWarmupPolicy(warmupPolicy),
+		service.WithNegativeCacheTTL(cfg.Cache.NegativeTTL),
+		service.WithCustomerPageCache(customerPages),
+		service.WithBusinessRules(businessRules),
+	)
+
+	readinessChecks := []ports.HealthCheck{
+		{Name: "postgres", Check: repo.Ping},
+		{Name: "kafka", Check: consumer.Ping},
+		{Name: "cache_restore", Check: service.CacheRestored},
+	}
+	if redisPing != nil {
+		readinessChecks = append(readinessChecks, ports.HealthCheck{Name: "redis", Check: redisPing})
+	}
 
 	var wg sync.WaitGroup
 
@@ -85,9 +236,14 @@ func main() {
 	go func() {
 		defer wg.Done()
 		logger.Info("[6/7] Starting HTTP Server")
-		server := http.NewServer(service, ":8081", logger)
+		server := http.NewServer(service, ":8081", logger.With("component", "http"),
+			http.WithReadinessChecks(cfg.Health.CheckTimeout, readinessChecks...),
+			http.WithRouteMiddleware(tracing.InstrumentRoute),
+			http.WithMetrics(appMetrics.Handler(), appMetrics.InstrumentRoute),
+			http.WithPIIMasking(redactor.Mask, cfg.PII.ReaderTokens...),
+		)
 		if err := server.RunServer(ctx); err != nil {
-			logger.Error("HTTP server failed", err)
+			logger.Error("HTTP server failed", "err", err)
 		}
 	}()
 
@@ -96,7 +252,7 @@ func main() {
 		defer wg.Done()
 		logger.Info("[7/7] Restoring Cache")
 		if err := service.Start(ctx); err != nil {
-			logger.Error("RestoreCacheService failed", err)
+			logger.Error("RestoreCacheService failed", "err", err)
 		}
 	}()
 
@@ -105,7 +261,27 @@ func main() {
 		defer wg.Done()
 		logger.Info("[8/8] Starting Kafka Consumer")
 		if err := service.SaveOrder(ctx); err != nil {
-			logger.Error("Kafka consumer service failed", err)
+			logger.Error("Kafka consumer service failed", "err", err)
+		}
+	}()
+
+	if tieredCache != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("Starting cache invalidation listener")
+			if err := tieredCache.Run(ctx); err != nil {
+				logger.Error("Cache invalidation listener failed", "err", err)
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		logger.Info("Starting Outbox Relay")
+		if err := relay.Run(ctx); err != nil {
+			logger.Error("Outbox relay failed", "err", err)
 		}
 	}()
 
@@ -119,11 +295,11 @@ func main() {
 		select {
 		case <-ticker.C:
 			if err := service.SendRandomOrder(ctx); err != nil {
-				logger.Error("Failed to send random order:", err)
+				logger.Error("Failed to send random order", "err", err)
 			}
 
 		case sig := <-sigChan:
-			logger.Info(fmt.Sprintf("Received signal %s, shutting down...", sig))
+			logger.Info("Received signal, shutting down...", "signal", sig.String())
 			cancel()
 			ticker.Stop()
 			wg.Wait()
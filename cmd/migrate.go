@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testberry/deployments/deployments/migrations"
+	"testberry/internal/adapters/postgres"
+	"testberry/internal/ports"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: order-service migrate up | down [N] | status | check | force VERSION"
+
+// runMigrate runs the migrate subcommand: up applies every pending migration,
+// down reverts the last N (default 1), status lists them, check reports the
+// rows that keep pending migrations from applying and force records a version
+// without running anything.
+func runMigrate(ctx context.Context, db *sql.DB, logger ports.Logger, args []string) error {
+	migrator, err := postgres.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch cmd, rest := args[0], args[1:]; {
+	case cmd == "up" && len(rest) == 0:
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migrations\n", applied)
+		return nil
+	case cmd == "down" && len(rest) <= 1:
+		steps := 1
+		if len(rest) == 1 {
+			if steps, err = strconv.Atoi(rest[0]); err != nil || steps < 1 {
+				return fmt.Errorf("down: N must be a positive number, got %q", rest[0])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case cmd == "status" && len(rest) == 0:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Dirty:
+				state = "dirty"
+			case s.Applied:
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, state)
+		}
+		return w.Flush()
+	case cmd == "check" && len(rest) == 0:
+		violations, err := migrator.Check(ctx)
+		if err != nil {
+			return err
+		}
+		for _, v := range violations {
+			fmt.Printf("%04d_%s: %s: %d rows\n", v.Version, v.Name, v.Violation, v.Rows)
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("%d checks of pending migrations failed", len(violations))
+		}
+		fmt.Println("pending migrations can be applied")
+		return nil
+	case cmd == "force" && len(rest) == 1:
+		version, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("force: VERSION must be a migration number or 0, got %q", rest[0])
+		}
+		return migrator.Force(ctx, version)
+	}
+	return errors.New(migrateUsage)
+}
+
+// migrateOnStart applies pending migrations before the repository is used.
+func migrateOnStart(ctx context.Context, db *sql.DB, logger ports.Logger) error {
+	migrator, err := postgres.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("Database schema is up to date", "applied", applied)
+	return nil
+}
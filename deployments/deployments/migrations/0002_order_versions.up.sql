@@ -0,0 +1,10 @@
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+
+CREATE TABLE order_versions (
+    order_uid TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    superseded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (order_uid, version)
+);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE orders ADD COLUMN status TEXT NOT NULL DEFAULT 'created';
\ No newline at end of file
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS orders_date_created_uid_idx ON orders (date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS item_order_uid_idx ON item (order_uid);
\ No newline at end of file
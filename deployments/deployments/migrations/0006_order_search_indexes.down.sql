@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS item_nm_id_idx;
+DROP INDEX IF EXISTS item_brand_idx;
+DROP INDEX IF EXISTS payment_bank_idx;
+DROP INDEX IF EXISTS payment_provider_idx;
+DROP INDEX IF EXISTS orders_payment_id_idx;
+DROP INDEX IF EXISTS orders_delivery_service_created_idx;
+DROP INDEX IF EXISTS orders_track_number_idx;
+DROP INDEX IF EXISTS orders_customer_created_idx;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE INDEX IF NOT EXISTS orders_customer_created_idx ON orders (customer_id, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS orders_track_number_idx ON orders (track_number);
+CREATE INDEX IF NOT EXISTS orders_delivery_service_created_idx ON orders (delivery_service, date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS orders_payment_id_idx ON orders (payment_id);
+CREATE INDEX IF NOT EXISTS payment_provider_idx ON payment (provider);
+CREATE INDEX IF NOT EXISTS payment_bank_idx ON payment (bank);
+CREATE INDEX IF NOT EXISTS item_brand_idx ON item (brand);
+CREATE INDEX IF NOT EXISTS item_nm_id_idx ON item (nm_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payment_request_id_idx;
+DROP INDEX IF EXISTS payment_transaction_idx;
+DROP INDEX IF EXISTS item_track_number_idx;
\ No newline at end of file
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS item_track_number_idx ON item (track_number);
+CREATE INDEX IF NOT EXISTS payment_transaction_idx ON payment (transaction);
+CREATE INDEX IF NOT EXISTS payment_request_id_idx ON payment (request_id);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE order_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+CREATE INDEX order_status_history_order_idx ON order_status_history (order_uid, id);
+
+
+INSERT INTO order_status_history (order_uid, from_status, to_status, changed_at)
+SELECT order_uid, NULL, status, COALESCE(date_created, now()) FROM orders;
\ No newline at end of file
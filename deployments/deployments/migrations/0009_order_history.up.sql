@@ -0,0 +1,58 @@
+CREATE TABLE order_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    snapshot JSONB,
+    source_topic TEXT,
+    source_partition INTEGER,
+    source_offset BIGINT,
+    source_caller TEXT,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+
+CREATE INDEX order_history_order_idx ON order_history (order_uid, recorded_at, id);
+
+
+CREATE FUNCTION order_history_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'order_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER order_history_append_only
+    BEFORE UPDATE OR DELETE ON order_history
+    FOR EACH ROW EXECUTE FUNCTION order_history_append_only();
+
+
+INSERT INTO order_history (order_uid, operation, snapshot)
+SELECT o.order_uid, 'backfill', jsonb_build_object(
+    'order_uid', o.order_uid,
+    'track_number', o.track_number,
+    'entry', o.entry,
+    'delivery', jsonb_build_object(
+        'name', d.name, 'phone', d.phone, 'zip', d.zip, 'city', d.city,
+        'address', d.address, 'region', d.region, 'email', d.email),
+    'payment', jsonb_build_object(
+        'transaction', p.transaction, 'request_id', p.request_id, 'currency', p.currency,
+        'provider', p.provider, 'amount', p.amount, 'payment_dt', p.payment_dt, 'bank', p.bank,
+        'delivery_cost', p.delivery_cost, 'goods_total', p.goods_total, 'custom_fee', p.custom_fee),
+    'items', COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+            'chrt_id', i.chrt_id, 'track_number', i.track_number, 'price', i.price, 'rid', i.rid,
+            'name', i.name, 'sale', i.sale, 'size', i.size, 'total_price', i.total_price,
+            'nm_id', i.nm_id, 'brand', i.brand, 'status', i.status) ORDER BY i.id)
+        FROM item i WHERE i.order_uid = o.order_uid), '[]'::jsonb),
+    'locale', o.locale,
+    'internal_signature', o.internal_signature,
+    'customer_id', o.customer_id,
+    'delivery_service', o.delivery_service,
+    'shardkey', o.shardkey,
+    'sm_id', o.sm_id,
+    'date_created', to_char(o.date_created, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+    'oof_shard', o.oof_shard,
+    'status', o.status)
+FROM orders o
+JOIN delivery d ON o.delivery_id = d.id
+JOIN payment p ON o.payment_id = p.id;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- Rows that would violate the constraints 0010_schema_hardening adds. The
+-- migrator refuses to apply it while any count is above zero.
+SELECT 'delivery: NULL column' AS violation, count(*) AS row_count FROM delivery WHERE NOT (delivery IS NOT NULL)
+UNION ALL
+SELECT 'payment: NULL column', count(*) FROM payment WHERE NOT (payment IS NOT NULL)
+UNION ALL
+SELECT 'orders: NULL column', count(*) FROM orders WHERE NOT (orders IS NOT NULL)
+UNION ALL
+SELECT 'item: NULL column', count(*) FROM item WHERE NOT (item IS NOT NULL)
+UNION ALL
+SELECT 'payment_currency_check: char_length(currency) = 3', count(*) FROM payment WHERE NOT (char_length(currency) = 3)
+UNION ALL
+SELECT 'payment_amount_check: amount >= 0', count(*) FROM payment WHERE NOT (amount >= 0)
+UNION ALL
+SELECT 'payment_delivery_cost_check: delivery_cost >= 0', count(*) FROM payment WHERE NOT (delivery_cost >= 0)
+UNION ALL
+SELECT 'payment_goods_total_check: goods_total >= 0', count(*) FROM payment WHERE NOT (goods_total >= 0)
+UNION ALL
+SELECT 'payment_custom_fee_check: custom_fee >= 0', count(*) FROM payment WHERE NOT (custom_fee >= 0)
+UNION ALL
+SELECT 'item_price_check: price >= 0', count(*) FROM item WHERE NOT (price >= 0)
+UNION ALL
+SELECT 'item_sale_check: sale >= 0', count(*) FROM item WHERE NOT (sale >= 0)
+UNION ALL
+SELECT 'item_total_price_check: total_price >= 0', count(*) FROM item WHERE NOT (total_price >= 0)
+UNION ALL
+SELECT 'item_status_check: status >= 0', count(*) FROM item WHERE NOT (status >= 0)
+UNION ALL
+SELECT 'orders_delivery_id_key: delivery_id shared by several orders', count(*) FROM (
+    SELECT delivery_id FROM orders WHERE delivery_id IS NOT NULL GROUP BY delivery_id HAVING count(*) > 1
+) shared
+UNION ALL
+SELECT 'orders_payment_id_key: payment_id shared by several orders', count(*) FROM (
+    SELECT payment_id FROM orders WHERE payment_id IS NOT NULL GROUP BY payment_id HAVING count(*) > 1
+) shared;
\ No newline at end of file
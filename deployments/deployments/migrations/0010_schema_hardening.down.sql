@@ -0,0 +1,83 @@
+DROP TRIGGER IF EXISTS orders_delete_parts ON orders;
+DROP FUNCTION IF EXISTS orders_delete_parts();
+
+
+ALTER TABLE item
+    DROP CONSTRAINT item_order_uid_fkey,
+    ADD CONSTRAINT item_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES orders(order_uid);
+
+
+CREATE INDEX IF NOT EXISTS orders_payment_id_idx ON orders (payment_id);
+
+
+ALTER TABLE orders
+    DROP CONSTRAINT IF EXISTS orders_delivery_id_key,
+    DROP CONSTRAINT IF EXISTS orders_payment_id_key;
+
+
+ALTER TABLE payment
+    DROP CONSTRAINT IF EXISTS payment_currency_check,
+    DROP CONSTRAINT IF EXISTS payment_amount_check,
+    DROP CONSTRAINT IF EXISTS payment_delivery_cost_check,
+    DROP CONSTRAINT IF EXISTS payment_goods_total_check,
+    DROP CONSTRAINT IF EXISTS payment_custom_fee_check;
+
+
+ALTER TABLE item
+    DROP CONSTRAINT IF EXISTS item_price_check,
+    DROP CONSTRAINT IF EXISTS item_sale_check,
+    DROP CONSTRAINT IF EXISTS item_total_price_check,
+    DROP CONSTRAINT IF EXISTS item_status_check;
+
+
+ALTER TABLE delivery
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL,
+    ALTER COLUMN zip DROP NOT NULL,
+    ALTER COLUMN city DROP NOT NULL,
+    ALTER COLUMN address DROP NOT NULL,
+    ALTER COLUMN region DROP NOT NULL,
+    ALTER COLUMN email DROP NOT NULL;
+
+
+ALTER TABLE payment
+    ALTER COLUMN transaction DROP NOT NULL,
+    ALTER COLUMN request_id DROP NOT NULL,
+    ALTER COLUMN currency DROP NOT NULL,
+    ALTER COLUMN provider DROP NOT NULL,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN payment_dt DROP NOT NULL,
+    ALTER COLUMN bank DROP NOT NULL,
+    ALTER COLUMN delivery_cost DROP NOT NULL,
+    ALTER COLUMN goods_total DROP NOT NULL,
+    ALTER COLUMN custom_fee DROP NOT NULL;
+
+
+ALTER TABLE orders
+    ALTER COLUMN track_number DROP NOT NULL,
+    ALTER COLUMN entry DROP NOT NULL,
+    ALTER COLUMN delivery_id DROP NOT NULL,
+    ALTER COLUMN payment_id DROP NOT NULL,
+    ALTER COLUMN locale DROP NOT NULL,
+    ALTER COLUMN internal_signature DROP NOT NULL,
+    ALTER COLUMN customer_id DROP NOT NULL,
+    ALTER COLUMN delivery_service DROP NOT NULL,
+    ALTER COLUMN shardkey DROP NOT NULL,
+    ALTER COLUMN sm_id DROP NOT NULL,
+    ALTER COLUMN date_created DROP NOT NULL,
+    ALTER COLUMN oof_shard DROP NOT NULL;
+
+
+ALTER TABLE item
+    ALTER COLUMN chrt_id DROP NOT NULL,
+    ALTER COLUMN track_number DROP NOT NULL,
+    ALTER COLUMN price DROP NOT NULL,
+    ALTER COLUMN rid DROP NOT NULL,
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN sale DROP NOT NULL,
+    ALTER COLUMN size DROP NOT NULL,
+    ALTER COLUMN total_price DROP NOT NULL,
+    ALTER COLUMN nm_id DROP NOT NULL,
+    ALTER COLUMN brand DROP NOT NULL,
+    ALTER COLUMN status DROP NOT NULL,
+    ALTER COLUMN order_uid DROP NOT NULL;
\ No newline at end of file
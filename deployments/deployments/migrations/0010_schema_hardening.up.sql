@@ -0,0 +1,95 @@
+ALTER TABLE delivery
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN phone SET NOT NULL,
+    ALTER COLUMN zip SET NOT NULL,
+    ALTER COLUMN city SET NOT NULL,
+    ALTER COLUMN address SET NOT NULL,
+    ALTER COLUMN region SET NOT NULL,
+    ALTER COLUMN email SET NOT NULL;
+
+
+ALTER TABLE payment
+    ALTER COLUMN transaction SET NOT NULL,
+    ALTER COLUMN request_id SET NOT NULL,
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN provider SET NOT NULL,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN payment_dt SET NOT NULL,
+    ALTER COLUMN bank SET NOT NULL,
+    ALTER COLUMN delivery_cost SET NOT NULL,
+    ALTER COLUMN goods_total SET NOT NULL,
+    ALTER COLUMN custom_fee SET NOT NULL;
+
+
+ALTER TABLE orders
+    ALTER COLUMN track_number SET NOT NULL,
+    ALTER COLUMN entry SET NOT NULL,
+    ALTER COLUMN delivery_id SET NOT NULL,
+    ALTER COLUMN payment_id SET NOT NULL,
+    ALTER COLUMN locale SET NOT NULL,
+    ALTER COLUMN internal_signature SET NOT NULL,
+    ALTER COLUMN customer_id SET NOT NULL,
+    ALTER COLUMN delivery_service SET NOT NULL,
+    ALTER COLUMN shardkey SET NOT NULL,
+    ALTER COLUMN sm_id SET NOT NULL,
+    ALTER COLUMN date_created SET NOT NULL,
+    ALTER COLUMN oof_shard SET NOT NULL;
+
+
+ALTER TABLE item
+    ALTER COLUMN chrt_id SET NOT NULL,
+    ALTER COLUMN track_number SET NOT NULL,
+    ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN rid SET NOT NULL,
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN sale SET NOT NULL,
+    ALTER COLUMN size SET NOT NULL,
+    ALTER COLUMN total_price SET NOT NULL,
+    ALTER COLUMN nm_id SET NOT NULL,
+    ALTER COLUMN brand SET NOT NULL,
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN order_uid SET NOT NULL;
+
+
+ALTER TABLE payment
+    ADD CONSTRAINT payment_currency_check CHECK (char_length(currency) = 3),
+    ADD CONSTRAINT payment_amount_check CHECK (amount >= 0),
+    ADD CONSTRAINT payment_delivery_cost_check CHECK (delivery_cost >= 0),
+    ADD CONSTRAINT payment_goods_total_check CHECK (goods_total >= 0),
+    ADD CONSTRAINT payment_custom_fee_check CHECK (custom_fee >= 0);
+
+
+ALTER TABLE item
+    ADD CONSTRAINT item_price_check CHECK (price >= 0),
+    ADD CONSTRAINT item_sale_check CHECK (sale >= 0),
+    ADD CONSTRAINT item_total_price_check CHECK (total_price >= 0),
+    ADD CONSTRAINT item_status_check CHECK (status >= 0);
+
+
+ALTER TABLE orders
+    ADD CONSTRAINT orders_delivery_id_key UNIQUE (delivery_id),
+    ADD CONSTRAINT orders_payment_id_key UNIQUE (payment_id);
+
+
+DROP INDEX IF EXISTS orders_payment_id_idx;
+
+
+ALTER TABLE item
+    DROP CONSTRAINT item_order_uid_fkey,
+    ADD CONSTRAINT item_order_uid_fkey FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+
+-- Every order owns its delivery and payment rows, which orders reference, so
+-- deleting the order deletes them too.
+CREATE FUNCTION orders_delete_parts() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM delivery WHERE id = OLD.delivery_id;
+    DELETE FROM payment WHERE id = OLD.payment_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER orders_delete_parts
+    AFTER DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION orders_delete_parts();
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE outbox ADD COLUMN claimed_until TIMESTAMPTZ;
+
+
+CREATE INDEX outbox_pending_aggregate_idx ON outbox (aggregate_id, id) WHERE published_at IS NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE order_history ADD COLUMN source_caller TEXT;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE order_history DROP COLUMN IF EXISTS source_caller;
\ No newline at end of file
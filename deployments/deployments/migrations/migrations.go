@@ -0,0 +1,8 @@
+// Package migrations embeds the schema migrations, NNNN_name.up.sql and
+// NNNN_name.down.sql, so the service can apply them itself.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
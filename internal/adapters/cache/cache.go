@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	order_entity "testberry/internal/domain/order"
 
@@ -11,16 +12,30 @@ import (
 
 type Cache struct {
 	client *redis.Client
+	ttl    time.Duration
 }
 
-func NewCache(addr, password string, db int) *Cache {
-	return &Cache{
+type Option func(*Cache)
+
+// WithTTL expires every key ttl after it was last set. Zero keeps keys forever.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+func NewCache(addr, password string, db int, opts ...Option) *Cache {
+	c := &Cache{
 		client: redis.NewClient(&redis.Options{
 			Addr:     addr,
 			Password: password,
 			DB:       db,
 		}),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *Cache) Set(ctx context.Context, order order_entity.Order) error {
@@ -28,7 +43,7 @@ func (c *Cache) Set(ctx context.Context, order order_entity.Order) error {
 	if err != nil {
 		return err
 	}
-	return c.client.Set(ctx, order.OrderUID, data, 0).Err()
+	return classifyError(c.client.Set(ctx, order.OrderUID, data, c.ttl).Err())
 }
 
 func (c *Cache) Get(ctx context.Context, orderUID string) (order_entity.Order, bool, error) {
@@ -37,7 +52,7 @@ func (c *Cache) Get(ctx context.Context, orderUID string) (order_entity.Order, b
 		return order_entity.Order{}, false, nil
 	}
 	if err != nil {
-		return order_entity.Order{}, false, err
+		return order_entity.Order{}, false, classifyError(err)
 	}
 	var order order_entity.Order
 	if err := json.Unmarshal([]byte(val), &order); err != nil {
@@ -45,3 +60,12 @@ func (c *Cache) Get(ctx context.Context, orderUID string) (order_entity.Order, b
 	}
 	return order, true, nil
 }
+
+func (c *Cache) Delete(ctx context.Context, orderUID string) error {
+	return classifyError(c.client.Del(ctx, orderUID).Err())
+}
+
+// Ping checks that Redis answers, for readiness probes.
+func (c *Cache) Ping(ctx context.Context) error {
+	return classifyError(c.client.Ping(ctx).Err())
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"testberry/internal/ports"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	customerPagesPrefix      = "customer_orders:"
+	customerGenerationPrefix = "customer_orders_gen:"
+)
+
+// setPageScript stores a page only if the customer's generation is still
+// ARGV[1], checked and written in one step.
+var setPageScript = redis.NewScript(`
+local gen = redis.call('GET', KEYS[2]) or '0'
+if gen ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[2], ARGV[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+return 1
+`)
+
+// CustomerPages keeps the history pages of a customer as fields of one Redis
+// hash, so dropping the hash drops them all at once.
+type CustomerPages struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewCustomerPages stores pages next to the orders of c. The hash of a
+// customer expires ttl after its last page was set; zero keeps it until it
+// is invalidated. Generations never expire.
+func NewCustomerPages(c *Cache, ttl time.Duration) *CustomerPages {
+	return &CustomerPages{client: c.client, ttl: ttl}
+}
+
+// Generation returns the customer's generation, zero until the first
+// invalidation. The counter is kept without a TTL, one small key per
+// invalidated customer, so it only ever grows: were it to expire, INCR would
+// count from 1 again and could match a generation read before it expired.
+func (p *CustomerPages) Generation(ctx context.Context, customerID string) (int64, error) {
+	gen, err := p.client.Get(ctx, customerGenerationPrefix+customerID).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return gen, classifyError(err)
+}
+
+func (p *CustomerPages) GetPage(ctx context.Context, customerID, pageKey string) (ports.CustomerOrdersPage, bool, error) {
+	val, err := p.client.HGet(ctx, customerPagesPrefix+customerID, pageKey).Result()
+	if err == redis.Nil {
+		return ports.CustomerOrdersPage{}, false, nil
+	}
+	if err != nil {
+		return ports.CustomerOrdersPage{}, false, classifyError(err)
+	}
+	var page ports.CustomerOrdersPage
+	if err := json.Unmarshal([]byte(val), &page); err != nil {
+		return ports.CustomerOrdersPage{}, false, err
+	}
+	return page, true, nil
+}
+
+func (p *CustomerPages) SetPage(ctx context.Context, customerID string, generation int64, pageKey string, page ports.CustomerOrdersPage) error {
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	keys := []string{customerPagesPrefix + customerID, customerGenerationPrefix + customerID}
+	err = setPageScript.Run(ctx, p.client, keys, generation, pageKey, data, p.ttl.Milliseconds()).Err()
+	return classifyError(err)
+}
+
+// InvalidateCustomer bumps the generation and drops the pages in one
+// transaction.
+func (p *CustomerPages) InvalidateCustomer(ctx context.Context, customerID string) error {
+	_, err := p.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, customerGenerationPrefix+customerID)
+		pipe.Del(ctx, customerPagesPrefix+customerID)
+		return nil
+	})
+	return classifyError(err)
+}
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testberry/internal/ports"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// classifyError marks everything except replies from the Redis server itself
+// (wrong type, OOM, ...) as transient: those are pool, dial and network failures.
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ports.ErrTransient) {
+		return err
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return err
+	}
+	cause := ports.ErrDependencyUnavailable
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		cause = ports.ErrTimeout
+	}
+	return fmt.Errorf("%w: %w: %w", ports.ErrTransient, cause, err)
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+)
+
+// MemoryCache is an in-process LRU cache of orders bounded by entry count
+// and by approximate size, the length of an order's JSON encoding. It is safe
+// for concurrent use.
+type MemoryCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	lru        *list.List
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	now        func() time.Time
+	stats      MemoryStats
+}
+
+// MemoryStats are the counters of a MemoryCache since it was created.
+type MemoryStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Expired   uint64 `json:"expired"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+}
+
+type memoryEntry struct {
+	order     order_entity.Order
+	size      int64
+	expiresAt time.Time
+}
+
+type MemoryOption func(*MemoryCache)
+
+// WithMaxEntries bounds the number of cached orders. Zero means no bound.
+func WithMaxEntries(n int) MemoryOption {
+	return func(c *MemoryCache) {
+		c.maxEntries = n
+	}
+}
+
+// WithMaxBytes bounds the approximate size of cached orders. Zero means no bound.
+func WithMaxBytes(n int64) MemoryOption {
+	return func(c *MemoryCache) {
+		c.maxBytes = n
+	}
+}
+
+// WithMemoryTTL expires entries ttl after they were last set. Zero keeps them
+// until they are evicted.
+func WithMemoryTTL(ttl time.Duration) MemoryOption {
+	return func(c *MemoryCache) {
+		c.ttl = ttl
+	}
+}
+
+func NewMemoryCache(opts ...MemoryOption) *MemoryCache {
+	c := &MemoryCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *MemoryCache) Set(_ context.Context, order order_entity.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	entry := &memoryEntry{order: order, size: int64(len(data))}
+	if c.ttl > 0 {
+		entry.expiresAt = c.now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBytes > 0 && entry.size > c.maxBytes {
+		// An order larger than the whole cache would only evict everything else.
+		c.remove(order.OrderUID)
+		return nil
+	}
+	if el, ok := c.entries[order.OrderUID]; ok {
+		c.bytes -= el.Value.(*memoryEntry).size
+		el.Value = entry
+		c.lru.MoveToFront(el)
+	} else {
+		c.entries[order.OrderUID] = c.lru.PushFront(entry)
+	}
+	c.bytes += entry.size
+
+	for c.overLimit() {
+		oldest := c.lru.Back()
+		c.remove(oldest.Value.(*memoryEntry).order.OrderUID)
+		c.stats.Evictions++
+	}
+	return nil
+}
+
+func (c *MemoryCache) Get(_ context.Context, orderUID string) (order_entity.Order, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[orderUID]
+	if !ok {
+		c.stats.Misses++
+		return order_entity.Order{}, false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(orderUID)
+		c.stats.Expired++
+		c.stats.Misses++
+		return order_entity.Order{}, false, nil
+	}
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+	return entry.order, true, nil
+}
+
+func (c *MemoryCache) Delete(_ context.Context, orderUID string) error {
+	c.drop(orderUID)
+	return nil
+}
+
+// Purge drops every entry.
+func (c *MemoryCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+func (c *MemoryCache) drop(orderUID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(orderUID)
+}
+
+func (c *MemoryCache) Stats() MemoryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+func (c *MemoryCache) overLimit() bool {
+	return (c.maxEntries > 0 && c.lru.Len() > c.maxEntries) ||
+		(c.maxBytes > 0 && c.bytes > c.maxBytes)
+}
+
+func (c *MemoryCache) remove(orderUID string) {
+	el, ok := c.entries[orderUID]
+	if !ok {
+		return
+	}
+	c.lru.Remove(el)
+	delete(c.entries, orderUID)
+	c.bytes -= el.Value.(*memoryEntry).size
+}
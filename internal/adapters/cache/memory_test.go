@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func orderWithUID(uid string) order_entity.Order {
+	order := testmock.Test_order
+	order.OrderUID = uid
+	return order
+}
+
+func TestMemoryCache_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache()
+
+	_, found, err := c.Get(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, c.Set(ctx, testmock.Test_order))
+	got, found, err := c.Get(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testmock.Test_order, got)
+
+	require.NoError(t, c.Delete(ctx, testmock.Test_order.OrderUID))
+	_, found, _ = c.Get(ctx, testmock.Test_order.OrderUID)
+	assert.False(t, found)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, int64(0), stats.Bytes)
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(WithMaxEntries(2))
+
+	require.NoError(t, c.Set(ctx, orderWithUID("a")))
+	require.NoError(t, c.Set(ctx, orderWithUID("b")))
+	_, found, _ := c.Get(ctx, "a")
+	require.True(t, found)
+	require.NoError(t, c.Set(ctx, orderWithUID("c")))
+
+	_, found, _ = c.Get(ctx, "b")
+	assert.False(t, found, "b was least recently used")
+	_, found, _ = c.Get(ctx, "a")
+	assert.True(t, found)
+	_, found, _ = c.Get(ctx, "c")
+	assert.True(t, found)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestMemoryCache_BoundedByBytes(t *testing.T) {
+	ctx := context.Background()
+	data, err := json.Marshal(orderWithUID("a"))
+	require.NoError(t, err)
+	c := NewMemoryCache(WithMaxBytes(int64(2*len(data) + len(data)/2)))
+
+	for _, uid := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Set(ctx, orderWithUID(uid)))
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, int64(2*len(data)), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Evictions)
+
+	small := NewMemoryCache(WithMaxBytes(10))
+	require.NoError(t, small.Set(ctx, orderWithUID("a")))
+	assert.Equal(t, 0, small.Stats().Entries, "an order larger than the cache is not stored")
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMemoryCache(WithMemoryTTL(time.Minute))
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, testmock.Test_order))
+	now = now.Add(59 * time.Second)
+	_, found, _ := c.Get(ctx, testmock.Test_order.OrderUID)
+	assert.True(t, found)
+
+	now = now.Add(time.Second)
+	_, found, _ = c.Get(ctx, testmock.Test_order.OrderUID)
+	assert.False(t, found)
+	assert.Equal(t, uint64(1), c.Stats().Expired)
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestMemoryCache_ConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache(WithMaxEntries(50))
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				uid := fmt.Sprintf("order-%d", (w*200+i)%100)
+				assert.NoError(t, c.Set(ctx, orderWithUID(uid)))
+				_, _, err := c.Get(ctx, uid)
+				assert.NoError(t, err)
+				if i%10 == 0 {
+					assert.NoError(t, c.Delete(ctx, uid))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, c.Stats().Entries, 50)
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// invalidationBus tells other instances that an order's cached copy changed.
+type invalidationBus interface {
+	Publish(ctx context.Context, orderUID string) error
+	// Subscribe calls invalidate for orders changed by other instances and
+	// resync when updates may have been missed, until ctx is done.
+	Subscribe(ctx context.Context, invalidate func(orderUID string), resync func()) error
+}
+
+// TieredCache keeps a small per-process copy of orders in front of a shared
+// cache. Writes go to the shared cache first and are then announced so other
+// instances drop their local copy. A local copy can still outlive a change by
+// a few milliseconds when the announcement races a read, so the local tier
+// should have a short TTL.
+type TieredCache struct {
+	local  *MemoryCache
+	remote ports.Cache
+	bus    invalidationBus
+}
+
+// NewTieredCache puts local in front of remote and announces changes on the
+// given Redis pub/sub channel of remote.
+func NewTieredCache(local *MemoryCache, remote *Cache, channel string, logger ports.Logger) *TieredCache {
+	return &TieredCache{
+		local:  local,
+		remote: remote,
+		bus:    &redisInvalidation{client: remote.client, channel: channel, instanceID: newInstanceID(), logger: logger},
+	}
+}
+
+func (c *TieredCache) Get(ctx context.Context, orderUID string) (order_entity.Order, bool, error) {
+	if order, found, _ := c.local.Get(ctx, orderUID); found {
+		return order, true, nil
+	}
+	order, found, err := c.remote.Get(ctx, orderUID)
+	if err != nil || !found {
+		return order, found, err
+	}
+	if err := c.local.Set(ctx, order); err != nil {
+		return order, true, err
+	}
+	return order, true, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, order order_entity.Order) error {
+	if err := c.remote.Set(ctx, order); err != nil {
+		c.local.drop(order.OrderUID)
+		return err
+	}
+	if err := c.local.Set(ctx, order); err != nil {
+		return err
+	}
+	return c.bus.Publish(ctx, order.OrderUID)
+}
+
+func (c *TieredCache) Delete(ctx context.Context, orderUID string) error {
+	c.local.drop(orderUID)
+	if err := c.remote.Delete(ctx, orderUID); err != nil {
+		return err
+	}
+	return c.bus.Publish(ctx, orderUID)
+}
+
+// Run drops local copies changed by other instances until ctx is done. After
+// a lost subscription the whole local tier is dropped, since announcements
+// sent in the meantime are gone.
+func (c *TieredCache) Run(ctx context.Context) error {
+	return c.bus.Subscribe(ctx, c.local.drop, c.local.Purge)
+}
+
+type redisInvalidation struct {
+	client     *redis.Client
+	channel    string
+	instanceID string
+	logger     ports.Logger
+}
+
+// Publish sends "<instance id> <order_uid>", so an instance can skip its own
+// announcements.
+func (b *redisInvalidation) Publish(ctx context.Context, orderUID string) error {
+	return classifyError(b.client.Publish(ctx, b.channel, b.instanceID+" "+orderUID).Err())
+}
+
+func (b *redisInvalidation) Subscribe(ctx context.Context, invalidate func(string), resync func()) error {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer func() {
+		if err := pubsub.Close(); err != nil {
+			b.logger.Error("failed to close redis subscription", "channel", b.channel, "err", err)
+		}
+	}()
+
+	subscribed := false
+	messages := pubsub.ChannelWithSubscriptions(ctx, 100)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			switch m := msg.(type) {
+			case *redis.Subscription:
+				// go-redis resubscribes after a reconnect; anything published
+				// while the connection was down was missed.
+				if subscribed {
+					resync()
+				}
+				subscribed = true
+			case *redis.Message:
+				source, orderUID, found := strings.Cut(m.Payload, " ")
+				if found && source != b.instanceID {
+					invalidate(orderUID)
+				}
+			}
+		}
+	}
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
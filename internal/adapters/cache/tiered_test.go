@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBus delivers announcements in-process between TieredCaches sharing it.
+type testBus struct {
+	mu          sync.Mutex
+	subscribers map[string]func(string)
+}
+
+type testBusEndpoint struct {
+	bus *testBus
+	id  string
+}
+
+func (e *testBusEndpoint) Publish(_ context.Context, orderUID string) error {
+	e.bus.mu.Lock()
+	defer e.bus.mu.Unlock()
+	for id, invalidate := range e.bus.subscribers {
+		if id != e.id {
+			invalidate(orderUID)
+		}
+	}
+	return nil
+}
+
+func (e *testBusEndpoint) Subscribe(ctx context.Context, invalidate func(string), _ func()) error {
+	e.bus.mu.Lock()
+	e.bus.subscribers[e.id] = invalidate
+	e.bus.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (b *testBus) subscribed(n int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers) == n
+}
+
+func newTestInstances(ctx context.Context, t *testing.T, remote *MemoryCache, n int) []*TieredCache {
+	bus := &testBus{subscribers: make(map[string]func(string))}
+	instances := make([]*TieredCache, n)
+	for i := range instances {
+		instances[i] = &TieredCache{
+			local:  NewMemoryCache(),
+			remote: remote,
+			bus:    &testBusEndpoint{bus: bus, id: newInstanceID()},
+		}
+		go func(c *TieredCache) {
+			assert.NoError(t, c.Run(ctx))
+		}(instances[i])
+	}
+	require.Eventually(t, func() bool { return bus.subscribed(n) }, time.Second, time.Millisecond)
+	return instances
+}
+
+func TestTieredCache_ReadsThroughToRemote(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := NewMemoryCache()
+	c := newTestInstances(ctx, t, remote, 1)[0]
+
+	_, found, err := c.Get(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, remote.Set(ctx, testmock.Test_order))
+	got, found, err := c.Get(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testmock.Test_order, got)
+
+	// The second read is served locally.
+	require.NoError(t, remote.Delete(ctx, testmock.Test_order.OrderUID))
+	_, found, _ = c.Get(ctx, testmock.Test_order.OrderUID)
+	assert.True(t, found)
+}
+
+func TestTieredCache_InvalidatesOtherInstances(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := NewMemoryCache()
+	instances := newTestInstances(ctx, t, remote, 2)
+	a, b := instances[0], instances[1]
+
+	require.NoError(t, a.Set(ctx, testmock.Test_order))
+	_, found, _ := b.Get(ctx, testmock.Test_order.OrderUID)
+	require.True(t, found)
+
+	changed := testmock.Test_order
+	changed.Delivery.City = "Haifa"
+	require.NoError(t, a.Set(ctx, changed))
+
+	got, found, err := b.Get(ctx, changed.OrderUID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Haifa", got.Delivery.City)
+
+	require.NoError(t, a.Delete(ctx, changed.OrderUID))
+	_, found, _ = b.Get(ctx, changed.OrderUID)
+	assert.False(t, found)
+	assert.Equal(t, 0, b.local.Stats().Entries)
+}
@@ -1,43 +1,300 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	order_entity "testberry/internal/domain/order"
 	"testberry/internal/ports"
+	"time"
 )
 
 type Handler struct {
-	service ports.OrderService
-	logger  ports.Logger
+	service   ports.OrderService
+	logger    ports.Logger
+	mask      func(field, value string) string
+	piiTokens [][]byte
 }
 
 func NewHandler(service ports.OrderService, logger ports.Logger) *Handler {
 	return &Handler{service: service, logger: logger}
 }
 
+// log returns the logger with the correlation fields of r, such as its
+// request_id.
+func (h *Handler) log(r *http.Request) ports.Logger {
+	return ports.LoggerFromContext(r.Context(), h.logger)
+}
+
+// problem is an RFC 7807 error body.
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// formattedOrder is the order followed by its amounts formatted for display,
+// returned by GET /order/{uid}?formatted=true.
+type formattedOrder struct {
+	order_entity.Order
+	Formatted order_entity.FormattedAmounts `json:"formatted"`
+}
+
+// GetOrder serves GET /order/{uid}. With as_of, an RFC 3339 time, it returns
+// the order as it was stored then; with formatted=true it adds the amounts
+// formatted for display.
 func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "application/json")
 	orderUID := strings.TrimPrefix(r.URL.Path, "/order/")
+	uid, timeline := strings.CutSuffix(orderUID, "/timeline")
+	if timeline {
+		orderUID = uid
+	}
 	if orderUID == "" {
-		http.Error(w, "Missing order UID", http.StatusBadRequest)
+		h.writeProblem(w, r, http.StatusBadRequest, "/problems/invalid-order-id", "Missing order UID", "")
+		return
+	}
+	r = r.WithContext(ports.ContextWithLogFields(r.Context(), "order_uid", orderUID))
+	if timeline {
+		h.orderTimeline(w, r, orderUID)
+		return
+	}
+
+	var (
+		order order_entity.Order
+		err   error
+	)
+	if s := r.URL.Query().Get("as_of"); s != "" {
+		var at time.Time
+		if at, err = time.Parse(time.RFC3339, s); err != nil {
+			h.writeError(w, r, fmt.Errorf("%w: as_of must be an RFC 3339 time", ports.ErrInvalidQuery))
+			return
+		}
+		order, err = h.service.GetOrderAsOf(r.Context(), orderUID, at)
+	} else {
+		order, err = h.service.GetOrder(r.Context(), orderUID)
+	}
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if mask := h.masker(w, r); mask != nil {
+		order = order.MaskPII(mask)
+	}
+	var body interface{} = order
+	if formatted, _ := strconv.ParseBool(r.URL.Query().Get("formatted")); formatted {
+		body = formattedOrder{Order: order, Formatted: order_entity.FormatAmounts(order)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.log(r).Error("failed to encode order to JSON", "err", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// orderTimeline serves GET /order/{uid}/timeline.
+func (h *Handler) orderTimeline(w http.ResponseWriter, r *http.Request, orderUID string) {
+	timeline, err := h.service.OrderTimeline(r.Context(), orderUID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		OrderUID string                      `json:"order_uid"`
+		Timeline []order_entity.StatusChange `json:"timeline"`
+	}{orderUID, timeline}); err != nil {
+		h.log(r).Error("failed to encode order timeline to JSON", "err", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	if len(orderUID) != 20 {
-		http.Error(w, "Invalid order UID lenght", http.StatusBadRequest)
+}
+
+// ListOrders serves GET /orders?customer_id=&track_number=&delivery_service=
+// &provider=&bank=&brand=&nm_id=&created_from=&created_to=&sort=&limit=&cursor=.
+// Dates are RFC 3339; sort is date_created or -date_created.
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	query, err := parseOrderQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, r, err)
 		return
 	}
 
-	order, err := h.service.GetOrder(r.Context(), orderUID)
+	page, err := h.service.ListOrders(r.Context(), query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, r, err)
 		return
 	}
+	if page.Orders == nil {
+		page.Orders = []order_entity.Order{}
+	}
+	if mask := h.masker(w, r); mask != nil {
+		page.Orders = maskOrders(page.Orders, mask)
+	}
 
-	if err := json.NewEncoder(w).Encode(order); err != nil {
-		h.logger.Error("failed to encode order to JSON", "err", err)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		h.log(r).Error("failed to encode orders to JSON", "err", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 }
+
+// CustomerOrders serves GET /customers/{customer_id}/orders?limit=&cursor=,
+// the customer's order summaries, newest first.
+func (h *Handler) CustomerOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	customerID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/customers/"), "/orders")
+	if !ok || customerID == "" || strings.Contains(customerID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	query := ports.CustomerOrdersQuery{CustomerID: customerID, Cursor: r.URL.Query().Get("cursor")}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(s); err != nil {
+			h.writeError(w, r, fmt.Errorf("%w: limit must be a number", ports.ErrInvalidQuery))
+			return
+		}
+	}
+
+	page, err := h.service.CustomerOrders(r.Context(), query)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if page.Orders == nil {
+		page.Orders = []ports.OrderSummary{}
+	}
+	if mask := h.masker(w, r); mask != nil {
+		orders := make([]ports.OrderSummary, len(page.Orders))
+		for i, summary := range page.Orders {
+			summary.DeliveryCity = mask(order_entity.PIICity, summary.DeliveryCity)
+			orders[i] = summary
+		}
+		page.Orders = orders
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		h.log(r).Error("failed to encode customer orders to JSON", "err", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// FindByTrackNumber serves GET /orders/by-track/{track_number}, matching the
+// order's and its items' track numbers.
+func (h *Handler) FindByTrackNumber(w http.ResponseWriter, r *http.Request) {
+	h.lookup(w, r, strings.TrimPrefix(r.URL.Path, "/orders/by-track/"), h.service.FindByTrackNumber)
+}
+
+// FindByPaymentID serves GET /orders/by-payment/{id}, matching the payment
+// transaction or request ID.
+func (h *Handler) FindByPaymentID(w http.ResponseWriter, r *http.Request) {
+	h.lookup(w, r, strings.TrimPrefix(r.URL.Path, "/orders/by-payment/"), h.service.FindByPaymentID)
+}
+
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request, value string,
+	find func(ctx context.Context, value string) ([]ports.OrderMatch, error)) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	matches, err := find(r.Context(), value)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if matches == nil {
+		matches = []ports.OrderMatch{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Orders []ports.OrderMatch `json:"orders"`
+	}{matches}); err != nil {
+		h.log(r).Error("failed to encode order matches to JSON", "err", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func parseOrderQuery(v url.Values) (ports.OrderQuery, error) {
+	query := ports.OrderQuery{
+		Filter: ports.OrderFilter{
+			CustomerID:      v.Get("customer_id"),
+			TrackNumber:     v.Get("track_number"),
+			DeliveryService: v.Get("delivery_service"),
+			PaymentProvider: v.Get("provider"),
+			PaymentBank:     v.Get("bank"),
+			ItemBrand:       v.Get("brand"),
+		},
+		Sort:   ports.OrderSort(v.Get("sort")),
+		Cursor: v.Get("cursor"),
+	}
+
+	var err error
+	if s := v.Get("limit"); s != "" {
+		if query.Limit, err = strconv.Atoi(s); err != nil {
+			return query, fmt.Errorf("%w: limit must be a number", ports.ErrInvalidQuery)
+		}
+	}
+	if s := v.Get("nm_id"); s != "" {
+		if query.Filter.ItemNmID, err = strconv.Atoi(s); err != nil {
+			return query, fmt.Errorf("%w: nm_id must be a number", ports.ErrInvalidQuery)
+		}
+	}
+	if s := v.Get("created_from"); s != "" {
+		if query.Filter.CreatedFrom, err = time.Parse(time.RFC3339, s); err != nil {
+			return query, fmt.Errorf("%w: created_from must be an RFC 3339 time", ports.ErrInvalidQuery)
+		}
+	}
+	if s := v.Get("created_to"); s != "" {
+		if query.Filter.CreatedTo, err = time.Parse(time.RFC3339, s); err != nil {
+			return query, fmt.Errorf("%w: created_to must be an RFC 3339 time", ports.ErrInvalidQuery)
+		}
+	}
+	return query, nil
+}
+
+// writeError maps service errors onto problem responses. Details of
+// unexpected errors are logged, not sent to the client.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ports.ErrInvalidOrderID):
+		h.writeProblem(w, r, http.StatusBadRequest, "/problems/invalid-order-id", "Invalid order UID", err.Error())
+	case errors.Is(err, ports.ErrInvalidQuery):
+		h.writeProblem(w, r, http.StatusBadRequest, "/problems/invalid-query", "Invalid order query", err.Error())
+	case errors.Is(err, ports.ErrOrderNotFound):
+		h.writeProblem(w, r, http.StatusNotFound, "/problems/order-not-found", "Order not found", "")
+	case errors.Is(err, ports.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		h.log(r).Error("order lookup timed out", "err", err)
+		h.writeProblem(w, r, http.StatusGatewayTimeout, "/problems/timeout", "Order lookup timed out", "")
+	case errors.Is(err, ports.ErrDependencyUnavailable):
+		h.log(r).Error("order storage unavailable", "err", err)
+		w.Header().Set("Retry-After", "1")
+		h.writeProblem(w, r, http.StatusServiceUnavailable, "/problems/dependency-unavailable", "Order storage is temporarily unavailable", "")
+	default:
+		h.log(r).Error("failed to get order", "err", err)
+		h.writeProblem(w, r, http.StatusInternalServerError, "/problems/internal", "Internal Server Error", "")
+	}
+}
+
+func (h *Handler) writeProblem(w http.ResponseWriter, r *http.Request, status int, typ, title, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	body := problem{Type: typ, Title: title, Status: status, Detail: detail, Instance: r.URL.Path}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.log(r).Error("failed to encode problem to JSON", "err", err)
+	}
+}
@@ -1,17 +1,24 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	"testberry/pkg/redact"
 	testmock "testberry/pkg/test"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandler_GetOrder(t *testing.T) {
@@ -20,7 +27,7 @@ func TestHandler_GetOrder(t *testing.T) {
 		url            string
 		setupMock      func(*testmock.MockOrderService)
 		expectedStatus int
-		expectedBody   string
+		expectedType   string
 		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
 	}{
 		{
@@ -38,8 +45,75 @@ func TestHandler_GetOrder(t *testing.T) {
 				err := json.Unmarshal(w.Body.Bytes(), &order)
 				assert.NoError(t, err)
 				assert.Equal(t, "12345678901234567890", order.OrderUID)
+				assert.NotContains(t, w.Body.String(), `"formatted"`)
+			},
+		},
+		{
+			name: "Заказ с отформатированными суммами",
+			url:  "/order/12345678901234567890?formatted=true",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(testmock.Test_order, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var body struct {
+					order_entity.Order
+					Formatted order_entity.FormattedAmounts `json:"formatted"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, testmock.Test_order.Payment.Amount, body.Payment.Amount, "raw amounts are unchanged")
+				assert.Equal(t, "18.17 USD", body.Formatted.Payment.Amount)
+				require.Len(t, body.Formatted.Items, 1)
+				assert.Equal(t, "3.17 USD", body.Formatted.Items[0].TotalPrice)
+			},
+		},
+		{
+			name: "История статусов заказа",
+			url:  "/order/12345678901234567890/timeline",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("OrderTimeline", mock.Anything, "12345678901234567890").Return([]order_entity.StatusChange{
+					{To: order_entity.StatusCreated, ChangedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+					{From: order_entity.StatusCreated, To: order_entity.StatusPaid, ChangedAt: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.JSONEq(t, `{"order_uid":"12345678901234567890","timeline":[
+					{"to":"created","changed_at":"2024-01-01T00:00:00Z"},
+					{"from":"created","to":"paid","changed_at":"2024-01-01T01:00:00Z"}]}`, w.Body.String())
+			},
+		},
+		{
+			name: "История статусов неизвестного заказа",
+			url:  "/order/12345678901234567890/timeline",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("OrderTimeline", mock.Anything, "12345678901234567890").
+					Return([]order_entity.StatusChange(nil), fmt.Errorf("%w: 12345678901234567890", ports.ErrOrderNotFound))
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "/problems/order-not-found",
+		},
+		{
+			name: "Заказ на момент времени",
+			url:  "/order/12345678901234567890?as_of=2024-01-01T12:00:00Z",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("GetOrderAsOf", mock.Anything, "12345678901234567890", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)).
+					Return(testmock.Test_order, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var order order_entity.Order
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+				assert.Equal(t, testmock.Test_order.OrderUID, order.OrderUID)
 			},
 		},
+		{
+			name:           "Некорректный момент времени",
+			url:            "/order/12345678901234567890?as_of=yesterday",
+			setupMock:      func(mockService *testmock.MockOrderService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
 		{
 			name: "Отсутствующий UID заказа",
 			url:  "/order/",
@@ -47,34 +121,64 @@ func TestHandler_GetOrder(t *testing.T) {
 
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Missing order UID\n",
+			expectedType:   "/problems/invalid-order-id",
 		},
 		{
-			name: "Некорректная длина UID заказа",
+			name: "Некорректный UID заказа",
 			url:  "/order/123",
 			setupMock: func(mockService *testmock.MockOrderService) {
-
+				mockService.On("GetOrder", mock.Anything, "123").Return(order_entity.Order{}, fmt.Errorf("%w: must be 20 characters long, got 3", ports.ErrInvalidOrderID))
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid order UID lenght\n",
+			expectedType:   "/problems/invalid-order-id",
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Contains(t, decodeProblem(t, w).Detail, "must be 20 characters long")
+			},
 		},
 		{
-			name: "Ошибка сервиса при получении заказа",
+			name: "Заказ не найден",
 			url:  "/order/12345678901234567890",
 			setupMock: func(mockService *testmock.MockOrderService) {
-				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(order_entity.Order{}, errors.New("database error"))
+				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(order_entity.Order{}, fmt.Errorf("%w: 12345678901234567890", ports.ErrOrderNotFound))
 			},
-			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   "database error\n",
+			expectedStatus: http.StatusNotFound,
+			expectedType:   "/problems/order-not-found",
 		},
 		{
-			name: "Пустой путь после /order/",
-			url:  "/order/",
+			name: "Хранилище недоступно",
+			url:  "/order/12345678901234567890",
 			setupMock: func(mockService *testmock.MockOrderService) {
-
+				err := fmt.Errorf("%w: %w: dial tcp: connection refused", ports.ErrTransient, ports.ErrDependencyUnavailable)
+				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(order_entity.Order{}, err)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedType:   "/problems/dependency-unavailable",
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "1", w.Header().Get("Retry-After"))
+				assert.NotContains(t, w.Body.String(), "connection refused")
+			},
+		},
+		{
+			name: "Превышено время ожидания",
+			url:  "/order/12345678901234567890",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				err := fmt.Errorf("%w: %w: i/o timeout", ports.ErrTransient, ports.ErrTimeout)
+				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(order_entity.Order{}, err)
+			},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedType:   "/problems/timeout",
+		},
+		{
+			name: "Ошибка сервиса при получении заказа",
+			url:  "/order/12345678901234567890",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("GetOrder", mock.Anything, "12345678901234567890").Return(order_entity.Order{}, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedType:   "/problems/internal",
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.NotContains(t, w.Body.String(), "database error")
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Missing order UID\n",
 		},
 	}
 
@@ -88,8 +192,13 @@ func TestHandler_GetOrder(t *testing.T) {
 			w := httptest.NewRecorder()
 			handler.GetOrder(w, req)
 			assert.Equal(t, tt.expectedStatus, w.Code)
-			if tt.expectedBody != "" {
-				assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedType != "" {
+				assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+				p := decodeProblem(t, w)
+				assert.Equal(t, tt.expectedType, p.Type)
+				assert.Equal(t, tt.expectedStatus, p.Status)
+				assert.Equal(t, req.URL.Path, p.Instance)
+				assert.NotEmpty(t, p.Title)
 			}
 
 			if tt.checkResponse != nil {
@@ -101,6 +210,13 @@ func TestHandler_GetOrder(t *testing.T) {
 	}
 }
 
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) problem {
+	t.Helper()
+	var p problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return p
+}
+
 func TestHandler_GetOrder_CORS_Headers(t *testing.T) {
 	mockService := new(testmock.MockOrderService)
 	testLogger := &testmock.TestLogger{}
@@ -121,6 +237,23 @@ func TestHandler_GetOrder_CORS_Headers(t *testing.T) {
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 }
 
+func TestHandler_GetOrder_LogsOrderUID(t *testing.T) {
+	withOrderUID := mock.MatchedBy(func(ctx context.Context) bool {
+		return assert.ObjectsAreEqual([]interface{}{"order_uid", "12345678901234567890"}, ports.LogFields(ctx))
+	})
+	mockService := new(testmock.MockOrderService)
+	mockService.On("GetOrder", withOrderUID, "12345678901234567890").Return(testmock.Test_order, nil)
+	mockService.On("OrderTimeline", withOrderUID, "12345678901234567890").Return([]order_entity.StatusChange{}, nil)
+	handler := NewHandler(mockService, &testmock.TestLogger{})
+
+	for _, url := range []string{"/order/12345678901234567890", "/order/12345678901234567890/timeline"} {
+		w := httptest.NewRecorder()
+		handler.GetOrder(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, w.Code, url)
+	}
+	mockService.AssertExpectations(t)
+}
+
 func TestNewHandler(t *testing.T) {
 	mockService := new(testmock.MockOrderService)
 	testLogger := &testmock.TestLogger{}
@@ -131,3 +264,293 @@ func TestNewHandler(t *testing.T) {
 	assert.Equal(t, mockService, handler.service)
 	assert.Equal(t, testLogger, handler.logger)
 }
+
+func TestHandler_ListOrders(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*testmock.MockOrderService)
+		expectedStatus int
+		expectedType   string
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Фильтры и пагинация передаются в сервис",
+			url:  "/orders?customer_id=test&provider=wbpay&brand=Vivienne+Sabo&nm_id=2389212&created_from=2024-01-01T00:00:00Z&sort=date_created&limit=10&cursor=abc",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				query := ports.OrderQuery{
+					Filter: ports.OrderFilter{
+						CustomerID:      "test",
+						PaymentProvider: "wbpay",
+						ItemBrand:       "Vivienne Sabo",
+						ItemNmID:        2389212,
+						CreatedFrom:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+					Sort:   ports.SortCreatedAsc,
+					Limit:  10,
+					Cursor: "abc",
+				}
+				page := ports.OrderPage{Orders: []order_entity.Order{testmock.Test_order}, NextCursor: "next"}
+				mockService.On("ListOrders", mock.Anything, query).Return(page, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				var page ports.OrderPage
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+				require.Len(t, page.Orders, 1)
+				assert.Equal(t, testmock.Test_order.OrderUID, page.Orders[0].OrderUID)
+				assert.Equal(t, "next", page.NextCursor)
+			},
+		},
+		{
+			name: "Пустая выдача",
+			url:  "/orders?customer_id=nobody",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("ListOrders", mock.Anything, ports.OrderQuery{Filter: ports.OrderFilter{CustomerID: "nobody"}}).Return(ports.OrderPage{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.JSONEq(t, `{"orders":[]}`, w.Body.String())
+			},
+		},
+		{
+			name:           "Некорректный limit",
+			url:            "/orders?limit=ten",
+			setupMock:      func(mockService *testmock.MockOrderService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
+		{
+			name:           "Некорректная дата",
+			url:            "/orders?created_to=yesterday",
+			setupMock:      func(mockService *testmock.MockOrderService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
+		{
+			name: "Сервис отклонил запрос",
+			url:  "/orders?sort=price",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("ListOrders", mock.Anything, ports.OrderQuery{Sort: "price"}).
+					Return(ports.OrderPage{}, fmt.Errorf("%w: unknown sort \"price\"", ports.ErrInvalidQuery))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(testmock.MockOrderService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService, &testmock.TestLogger{})
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.ListOrders(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, decodeProblem(t, w).Type)
+			}
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_CustomerOrders(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*testmock.MockOrderService)
+		expectedStatus int
+		expectedType   string
+		checkResponse  func(*testing.T, *httptest.ResponseRecorder)
+	}{
+		{
+			name: "История заказов клиента",
+			url:  "/customers/test/orders?limit=10&cursor=abc",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				query := ports.CustomerOrdersQuery{CustomerID: "test", Limit: 10, Cursor: "abc"}
+				page := ports.CustomerOrdersPage{
+					Orders:     []ports.OrderSummary{{OrderUID: testmock.Test_order.OrderUID, TotalAmount: 1817, Currency: "USD", ItemCount: 1, DeliveryCity: "Kiryat Mozkin"}},
+					NextCursor: "next",
+				}
+				mockService.On("CustomerOrders", mock.Anything, query).Return(page, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+				var page ports.CustomerOrdersPage
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+				require.Len(t, page.Orders, 1)
+				assert.Equal(t, 1817, page.Orders[0].TotalAmount)
+				assert.Equal(t, "next", page.NextCursor)
+			},
+		},
+		{
+			name: "У клиента нет заказов",
+			url:  "/customers/nobody/orders",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("CustomerOrders", mock.Anything, ports.CustomerOrdersQuery{CustomerID: "nobody"}).Return(ports.CustomerOrdersPage{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.JSONEq(t, `{"orders":[]}`, w.Body.String())
+			},
+		},
+		{
+			name:           "Некорректный limit",
+			url:            "/customers/test/orders?limit=ten",
+			setupMock:      func(mockService *testmock.MockOrderService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
+		{
+			name:           "Неизвестный путь",
+			url:            "/customers/test",
+			setupMock:      func(mockService *testmock.MockOrderService) {},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(testmock.MockOrderService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService, &testmock.TestLogger{})
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			handler.CustomerOrders(w, req)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, decodeProblem(t, w).Type)
+			}
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, w)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_FindOrders(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		setupMock      func(*testmock.MockOrderService)
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name: "Несколько заказов по трек-номеру",
+			url:  "/orders/by-track/WBILMTESTTRACK",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("FindByTrackNumber", mock.Anything, "WBILMTESTTRACK").Return([]ports.OrderMatch{
+					{OrderUID: "a", DateCreated: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), MatchedBy: []string{ports.MatchItemTrackNumber}},
+					{OrderUID: "b", DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), MatchedBy: []string{ports.MatchOrderTrackNumber}},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"orders":[
+				{"order_uid":"a","date_created":"2024-01-02T00:00:00Z","matched_by":["item.track_number"]},
+				{"order_uid":"b","date_created":"2024-01-01T00:00:00Z","matched_by":["orders.track_number"]}]}`,
+		},
+		{
+			name: "Платёж не найден",
+			url:  "/orders/by-payment/unknown",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("FindByPaymentID", mock.Anything, "unknown").Return([]ports.OrderMatch(nil), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"orders":[]}`,
+		},
+		{
+			name: "Пустой идентификатор платежа",
+			url:  "/orders/by-payment/",
+			setupMock: func(mockService *testmock.MockOrderService) {
+				mockService.On("FindByPaymentID", mock.Anything, "").
+					Return([]ports.OrderMatch(nil), fmt.Errorf("%w: payment id is required", ports.ErrInvalidQuery))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "/problems/invalid-query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(testmock.MockOrderService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService, &testmock.TestLogger{})
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+			if strings.HasPrefix(tt.url, "/orders/by-track/") {
+				handler.FindByTrackNumber(w, req)
+			} else {
+				handler.FindByPaymentID(w, req)
+			}
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedType != "" {
+				assert.Equal(t, tt.expectedType, decodeProblem(t, w).Type)
+			}
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_PIIMasking(t *testing.T) {
+	redactor := redact.New(redact.DefaultPolicies(), nil)
+	tests := []struct {
+		name          string
+		authorization string
+		masked        bool
+	}{
+		{name: "Без токена данные маскируются", masked: true},
+		{name: "Неверный токен не даёт доступа", authorization: "Bearer wrong", masked: true},
+		{name: "Токен без схемы Bearer не принимается", authorization: "pii-token", masked: true},
+		{name: "Роль pii_reader видит данные", authorization: "Bearer pii-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(testmock.MockOrderService)
+			mockService.On("GetOrder", mock.Anything, testmock.Test_order.OrderUID).Return(testmock.Test_order, nil)
+			mockService.On("ListOrders", mock.Anything, mock.Anything).Return(ports.OrderPage{Orders: []order_entity.Order{testmock.Test_order}}, nil)
+			handler := NewServer(mockService, ":8081", &testmock.TestLogger{},
+				WithPIIMasking(redactor.Mask, "pii-token", "")).handler
+
+			want := testmock.Test_order.Delivery
+			if tt.masked {
+				want = want.MaskPII(redactor.Mask)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/order/"+testmock.Test_order.OrderUID, nil)
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+			handler.GetOrder(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Authorization", w.Header().Get("Vary"))
+			var order order_entity.Order
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &order))
+			assert.Equal(t, want, order.Delivery)
+
+			req = httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			w = httptest.NewRecorder()
+			handler.ListOrders(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			var page ports.OrderPage
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			require.Len(t, page.Orders, 1)
+			assert.Equal(t, want, page.Orders[0].Delivery)
+		})
+	}
+
+	assert.Equal(t, "Test User", testmock.Test_order.Delivery.Name, "the service's order is not modified")
+}
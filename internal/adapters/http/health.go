@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testberry/internal/ports"
+	"time"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+type checkResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// healthz serves GET /healthz: the process is up and serving HTTP.
+func healthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status":"ok"}`))
+}
+
+// readyz serves GET /readyz. It runs every check concurrently, each bounded
+// by timeout, and answers 503 unless all of them pass.
+func readyz(checks []ports.HealthCheck, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body := readiness{Status: "ready", Checks: make(map[string]checkResult, len(checks))}
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for _, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				start := time.Now()
+				err := check.Check(ctx)
+				result := checkResult{Status: "ok", DurationMS: time.Since(start).Milliseconds()}
+				if err != nil {
+					result.Status, result.Error = "failed", err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				body.Checks[check.Name] = result
+				if err != nil {
+					body.Status = "not ready"
+				}
+			}()
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if body.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
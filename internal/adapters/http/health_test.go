@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"testberry/internal/ports"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name           string
+		checks         []ports.HealthCheck
+		expectedStatus int
+		expectedChecks map[string]string
+	}{
+		{
+			name:           "Все зависимости доступны",
+			checks:         []ports.HealthCheck{{Name: "postgres", Check: ok}, {Name: "redis", Check: ok}},
+			expectedStatus: http.StatusOK,
+			expectedChecks: map[string]string{"postgres": "ok", "redis": "ok"},
+		},
+		{
+			name:           "Одна зависимость недоступна",
+			checks:         []ports.HealthCheck{{Name: "postgres", Check: ok}, {Name: "kafka", Check: down}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{"postgres": "ok", "kafka": "failed"},
+		},
+		{
+			name:           "Проверка не уложилась в таймаут",
+			checks:         []ports.HealthCheck{{Name: "redis", Check: hang}},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedChecks: map[string]string{"redis": "failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			readyz(tt.checks, 50*time.Millisecond)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var body readiness
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			for name, status := range tt.expectedChecks {
+				assert.Equal(t, status, body.Checks[name].Status, name)
+				if status == "failed" {
+					assert.NotEmpty(t, body.Checks[name].Error, name)
+				}
+			}
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "ready", body.Status)
+			} else {
+				assert.Equal(t, "not ready", body.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	order_entity "testberry/internal/domain/order"
+)
+
+// Caller roles. Only a pii_reader sees personal data unmasked.
+const (
+	rolePublic    = "public"
+	rolePIIReader = "pii_reader"
+)
+
+// role returns rolePIIReader for callers presenting one of the PII tokens as
+// "Authorization: Bearer <token>", and rolePublic for everyone else.
+func (h *Handler) role(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return rolePublic
+	}
+	for _, t := range h.piiTokens {
+		if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+			return rolePIIReader
+		}
+	}
+	return rolePublic
+}
+
+// masker returns how personal data is masked in the response to r, or nil
+// when it is sent as is.
+func (h *Handler) masker(w http.ResponseWriter, r *http.Request) func(field, value string) string {
+	if h.mask == nil {
+		return nil
+	}
+	w.Header().Add("Vary", "Authorization")
+	if h.role(r) == rolePIIReader {
+		return nil
+	}
+	return h.mask
+}
+
+func maskOrders(orders []order_entity.Order, mask func(field, value string) string) []order_entity.Order {
+	masked := make([]order_entity.Order, len(orders))
+	for i, order := range orders {
+		masked[i] = order.MaskPII(mask)
+	}
+	return masked
+}
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"testberry/internal/ports"
+)
+
+const headerRequestID = "X-Request-ID"
+
+// withRequestID tags the request's log records with a request_id: the
+// caller's X-Request-ID, or a new one. It is echoed in the response.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(headerRequestID)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set(headerRequestID, id)
+		ctx := ports.ContextWithLogFields(r.Context(), "request_id", id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
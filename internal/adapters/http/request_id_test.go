@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"testberry/internal/ports"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{name: "берёт X-Request-ID клиента", header: "client-request-1", keep: true},
+		{name: "генерирует id без заголовка", header: ""},
+		{name: "заменяет слишком длинный id", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []interface{}
+			handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fields = ports.LogFields(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/order/x", nil)
+			if tt.header != "" {
+				req.Header.Set(headerRequestID, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(headerRequestID)
+			if tt.keep {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.Len(t, id, 16)
+			}
+			assert.Equal(t, []interface{}{"request_id", id}, fields)
+		})
+	}
+}
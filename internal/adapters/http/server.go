@@ -2,37 +2,107 @@ package http
 
 import (
 	"context"
-	"log"
+	"expvar"
 	"net/http"
 	"os"
 	"testberry/internal/ports"
+	"time"
 )
 
 type Server struct {
-	handler *Handler
-	addr    string
-	logger  ports.Logger
+	handler      *Handler
+	addr         string
+	logger       ports.Logger
+	checks       []ports.HealthCheck
+	checkTimeout time.Duration
+	middleware   []RouteMiddleware
+	metrics      http.Handler
 }
 
-func NewServer(service ports.OrderService, addr string, logger ports.Logger) *Server {
-	return &Server{
-		handler: NewHandler(service, logger),
-		addr:    addr,
-		logger:  logger,
+type ServerOption func(*Server)
+
+// RouteMiddleware wraps the handler of a route, given the route's pattern.
+type RouteMiddleware func(route string, next http.Handler) http.Handler
+
+// WithRouteMiddleware wraps every route's handler with mw. Middleware added
+// first runs outermost.
+func WithRouteMiddleware(mw RouteMiddleware) ServerOption {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, mw)
+	}
+}
+
+// WithMetrics wraps every route's handler with instrument and serves metrics
+// on /metrics.
+func WithMetrics(metrics http.Handler, instrument RouteMiddleware) ServerOption {
+	return func(s *Server) {
+		s.metrics = metrics
+		s.middleware = append(s.middleware, instrument)
 	}
 }
 
+// WithReadinessChecks makes /readyz report ready only while every check
+// passes within timeout.
+func WithReadinessChecks(timeout time.Duration, checks ...ports.HealthCheck) ServerOption {
+	return func(s *Server) {
+		s.checkTimeout = timeout
+		s.checks = append(s.checks, checks...)
+	}
+}
+
+// WithPIIMasking masks personal data in responses by mask, except for callers
+// presenting one of tokens as a bearer token.
+func WithPIIMasking(mask func(field, value string) string, tokens ...string) ServerOption {
+	return func(s *Server) {
+		s.handler.mask = mask
+		for _, token := range tokens {
+			if token != "" {
+				s.handler.piiTokens = append(s.handler.piiTokens, []byte(token))
+			}
+		}
+	}
+}
+
+func NewServer(service ports.OrderService, addr string, logger ports.Logger, opts ...ServerOption) *Server {
+	s := &Server{
+		handler:      NewHandler(service, logger),
+		addr:         addr,
+		logger:       logger,
+		checkTimeout: defaultCheckTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 func (s *Server) RunServer(ctx context.Context) error {
 	if s.addr == "" {
 		s.logger.Error("Addr is not set", "addr", s.addr)
 		os.Exit(1)
 	}
 	mux := http.NewServeMux()
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("front"))))
-	mux.HandleFunc("/order/", s.handler.GetOrder)
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	handle := func(route string, handler http.Handler) {
+		for i := len(s.middleware) - 1; i >= 0; i-- {
+			handler = s.middleware[i](route, handler)
+		}
+		mux.Handle(route, withRequestID(handler))
+	}
+	handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("front"))))
+	handle("/order/", http.HandlerFunc(s.handler.GetOrder))
+	handle("/orders", http.HandlerFunc(s.handler.ListOrders))
+	handle("/orders/by-track/", http.HandlerFunc(s.handler.FindByTrackNumber))
+	handle("/orders/by-payment/", http.HandlerFunc(s.handler.FindByPaymentID))
+	handle("/customers/", http.HandlerFunc(s.handler.CustomerOrders))
+	handle("/healthz", http.HandlerFunc(healthz))
+	handle("/readyz", readyz(s.checks, s.checkTimeout))
+	handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "front/index.html")
-	})
+	}))
+	mux.Handle("/debug/vars", expvar.Handler())
+	if s.metrics != nil {
+		mux.Handle("/metrics", s.metrics)
+	}
 	server := &http.Server{
 		Addr:    s.addr,
 		Handler: mux,
@@ -40,7 +110,8 @@ func (s *Server) RunServer(ctx context.Context) error {
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("HTTP server failed: %v", err)
+			s.logger.Error("HTTP server failed", "err", err)
+			os.Exit(1)
 		}
 	}()
 	<-ctx.Done()
@@ -2,36 +2,128 @@ package messagebrok
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalKey       = "x-original-key"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderErrorClass        = "x-error-class"
+	HeaderErrorMessage      = "x-error-message"
+	// HeaderViolatedRules lists, comma-separated, the business rules a
+	// rejected order violated.
+	HeaderViolatedRules = "x-violated-rules"
+)
+
+type DeadLetterProducer interface {
+	SendWithHeaders(ctx context.Context, key string, value []byte, headers map[string]string) error
+}
+
 type ConsumerGroupHandler struct {
 	handlerFunc func(ctx context.Context, message []byte) error
+	deadLetter  DeadLetterProducer
+	logger      ports.Logger
+	// member, if set, tracks whether the consumer holds a group session.
+	member *atomic.Bool
 }
 
-func (h ConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (h ConsumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
+func (h ConsumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error {
+	if h.member != nil {
+		h.member.Store(true)
+	}
+	return nil
+}
+
+func (h ConsumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error {
+	if h.member != nil {
+		h.member.Store(false)
+	}
+	return nil
+}
 
 func (h ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	for msg := range claim.Messages() {
-		err := h.handlerFunc(session.Context(), msg.Value)
+		// The handler continues the producer's trace, when the record carries one.
+		ctx := otel.GetTextMapPropagator().Extract(session.Context(), consumerCarrier{msg: msg})
+		ctx, span := tracer.Start(ctx, "process "+msg.Topic,
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(messagingAttributes(msg.Topic, msg.Partition, msg.Offset)...),
+		)
+		ctx = ports.ContextWithLogFields(ctx, "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset)
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = ports.ContextWithLogFields(ctx, "trace_id", sc.TraceID().String())
+		}
+		ctx = ports.ContextWithMessage(ctx, ports.MessageMetadata{
+			Topic:         msg.Topic,
+			Partition:     msg.Partition,
+			Offset:        msg.Offset,
+			Key:           msg.Key,
+			HighWaterMark: claim.HighWaterMarkOffset(),
+		})
+		err := h.handlerFunc(ctx, msg.Value)
+		endSpan(span, err)
 		if err != nil {
-			log.Printf("Ошибка обработки сообщения: %v", err)
-			continue
+			if session.Context().Err() != nil {
+				// Shutting down: leave the offset unmarked so the message is redelivered.
+				return nil
+			}
+			logger := ports.LoggerFromContext(ctx, h.logger)
+			if h.deadLetter == nil {
+				logger.Error("Failed to process message", "err", err)
+				continue
+			}
+			if dlqErr := h.deadLetter.SendWithHeaders(ctx, string(msg.Key), msg.Value, deadLetterHeaders(msg, err)); dlqErr != nil {
+				return fmt.Errorf("failed to publish message at offset %d to dead-letter topic: %w", msg.Offset, dlqErr)
+			}
+			logger.Warn("Message moved to dead-letter topic", "err", err)
 		}
 		session.MarkMessage(msg, "")
 	}
 	return nil
 }
 
+func deadLetterHeaders(msg *sarama.ConsumerMessage, err error) map[string]string {
+	class := ports.ErrorClassUnknown
+	var msgErr *ports.MessageError
+	if errors.As(err, &msgErr) {
+		class = msgErr.Class
+	}
+	headers := map[string]string{
+		HeaderOriginalTopic:     msg.Topic,
+		HeaderOriginalKey:       string(msg.Key),
+		HeaderOriginalPartition: strconv.FormatInt(int64(msg.Partition), 10),
+		HeaderOriginalOffset:    strconv.FormatInt(msg.Offset, 10),
+		HeaderErrorClass:        class,
+		HeaderErrorMessage:      err.Error(),
+	}
+	if rules := order_entity.ViolatedRules(err); len(rules) > 0 {
+		headers[HeaderViolatedRules] = strings.Join(rules, ",")
+	}
+	return headers
+}
+
 type Consumer struct {
 	consumerGroup sarama.ConsumerGroup
+	groupID       string
 	topic         string
+	deadLetter    DeadLetterProducer
+	logger        ports.Logger
+	member        atomic.Bool
 }
 
-func NewConsumer(brokers []string, groupID, topic string) (*Consumer, error) {
+func NewConsumer(brokers []string, groupID, topic string, deadLetter DeadLetterProducer, logger ports.Logger) (*Consumer, error) {
 	config := sarama.NewConfig()
 	config.Version = sarama.V2_8_0_0
 	config.Consumer.Offsets.Initial = sarama.OffsetNewest
@@ -44,17 +136,20 @@ func NewConsumer(brokers []string, groupID, topic string) (*Consumer, error) {
 
 	return &Consumer{
 		consumerGroup: consumerGroup,
+		groupID:       groupID,
 		topic:         topic,
+		deadLetter:    deadLetter,
+		logger:        logger,
 	}, nil
 }
 
 func (c *Consumer) Consume(ctx context.Context, handler func(ctx context.Context, message []byte) error) error {
-	h := ConsumerGroupHandler{handlerFunc: handler}
+	h := ConsumerGroupHandler{handlerFunc: handler, deadLetter: c.deadLetter, logger: c.logger, member: &c.member}
 
 	for {
 		err := c.consumerGroup.Consume(ctx, []string{c.topic}, h)
 		if err != nil {
-			log.Printf("Ошибка в consumer group: %v", err)
+			c.logger.Error("Consumer group failed", "group", c.groupID, "err", err)
 			return err
 		}
 
@@ -64,6 +159,15 @@ func (c *Consumer) Consume(ctx context.Context, handler func(ctx context.Context
 	}
 }
 
+// Ping reports whether the consumer currently belongs to its consumer group,
+// for readiness probes. It doesn't while joining or rebalancing.
+func (c *Consumer) Ping(_ context.Context) error {
+	if !c.member.Load() {
+		return fmt.Errorf("not a member of consumer group %s", c.groupID)
+	}
+	return nil
+}
+
 func (c *Consumer) Close() error {
 	return c.consumerGroup.Close()
 }
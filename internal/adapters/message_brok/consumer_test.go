@@ -0,0 +1,202 @@
+package messagebrok
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSession struct {
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *testSession) Claims() map[string][]int32                       { return nil }
+func (s *testSession) MemberID() string                                 { return "test-member" }
+func (s *testSession) GenerationID() int32                              { return 1 }
+func (s *testSession) MarkOffset(_ string, _ int32, _ int64, _ string)  {}
+func (s *testSession) Commit()                                          {}
+func (s *testSession) ResetOffset(_ string, _ int32, _ int64, _ string) {}
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+func (s *testSession) Context() context.Context { return s.ctx }
+
+type testClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func newTestClaim(msgs ...*sarama.ConsumerMessage) *testClaim {
+	ch := make(chan *sarama.ConsumerMessage, len(msgs))
+	for _, m := range msgs {
+		ch <- m
+	}
+	close(ch)
+	return &testClaim{messages: ch}
+}
+
+func (c *testClaim) Topic() string                            { return "orders" }
+func (c *testClaim) Partition() int32                         { return 0 }
+func (c *testClaim) InitialOffset() int64                     { return 0 }
+func (c *testClaim) HighWaterMarkOffset() int64               { return 0 }
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+type sentMessage struct {
+	key     string
+	value   []byte
+	headers map[string]string
+}
+
+type testDeadLetter struct {
+	sent []sentMessage
+	err  error
+}
+
+func (d *testDeadLetter) SendWithHeaders(_ context.Context, key string, value []byte, headers map[string]string) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.sent = append(d.sent, sentMessage{key: key, value: value, headers: headers})
+	return nil
+}
+
+func TestConsumeClaim_SuccessMarksMessage(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	dlq := &testDeadLetter{}
+	h := ConsumerGroupHandler{
+		logger:      &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error { return nil },
+		deadLetter:  dlq,
+	}
+
+	err := h.ConsumeClaim(session, newTestClaim(&sarama.ConsumerMessage{Topic: "orders", Offset: 7, Value: []byte(`{}`)}))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{7}, session.marked)
+	assert.Empty(t, dlq.sent)
+}
+
+func TestConsumeClaim_FailedMessageGoesToDeadLetter(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	dlq := &testDeadLetter{}
+	h := ConsumerGroupHandler{
+		logger: &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error {
+			var v map[string]interface{}
+			if err := json.Unmarshal(message, &v); err != nil {
+				return &ports.MessageError{Class: ports.ErrorClassUnmarshal, Err: err}
+			}
+			return nil
+		},
+		deadLetter: dlq,
+	}
+
+	msg := &sarama.ConsumerMessage{Topic: "orders", Partition: 2, Offset: 42, Key: []byte("order-key"), Value: []byte(`{invalid_json}`)}
+	err := h.ConsumeClaim(session, newTestClaim(msg))
+	require.NoError(t, err)
+
+	require.Len(t, dlq.sent, 1)
+	sent := dlq.sent[0]
+	assert.Equal(t, "order-key", sent.key)
+	assert.Equal(t, msg.Value, sent.value)
+	assert.Equal(t, "orders", sent.headers[HeaderOriginalTopic])
+	assert.Equal(t, "order-key", sent.headers[HeaderOriginalKey])
+	assert.Equal(t, "2", sent.headers[HeaderOriginalPartition])
+	assert.Equal(t, "42", sent.headers[HeaderOriginalOffset])
+	assert.Equal(t, ports.ErrorClassUnmarshal, sent.headers[HeaderErrorClass])
+	assert.Contains(t, sent.headers[HeaderErrorMessage], "invalid character")
+	assert.Equal(t, []int64{42}, session.marked)
+}
+
+func TestConsumeClaim_UnclassifiedError(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	dlq := &testDeadLetter{}
+	h := ConsumerGroupHandler{
+		logger:      &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error { return errors.New("boom") },
+		deadLetter:  dlq,
+	}
+
+	err := h.ConsumeClaim(session, newTestClaim(&sarama.ConsumerMessage{Topic: "orders", Offset: 1}))
+	require.NoError(t, err)
+	require.Len(t, dlq.sent, 1)
+	assert.Equal(t, ports.ErrorClassUnknown, dlq.sent[0].headers[HeaderErrorClass])
+	assert.Equal(t, "boom", dlq.sent[0].headers[HeaderErrorMessage])
+}
+
+func TestConsumeClaim_ViolatedRulesInHeaders(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	dlq := &testDeadLetter{}
+	violations := &order_entity.ValidationError{Violations: []order_entity.Violation{
+		{Rule: order_entity.RuleGoodsTotal, Field: "payment.goods_total"},
+		{Rule: order_entity.RuleItemTrackNumber, Field: "items[0].track_number"},
+		{Rule: order_entity.RuleItemTrackNumber, Field: "items[1].track_number"},
+	}}
+	h := ConsumerGroupHandler{
+		logger: &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error {
+			return &ports.MessageError{Class: ports.ErrorClassValidation, Err: violations}
+		},
+		deadLetter: dlq,
+	}
+
+	err := h.ConsumeClaim(session, newTestClaim(&sarama.ConsumerMessage{Topic: "orders", Offset: 1}))
+	require.NoError(t, err)
+	require.Len(t, dlq.sent, 1)
+	assert.Equal(t, ports.ErrorClassValidation, dlq.sent[0].headers[HeaderErrorClass])
+	assert.Equal(t, order_entity.RuleGoodsTotal+","+order_entity.RuleItemTrackNumber, dlq.sent[0].headers[HeaderViolatedRules])
+}
+
+func TestConsumeClaim_DeadLetterFailureKeepsOffset(t *testing.T) {
+	session := &testSession{ctx: context.Background()}
+	dlq := &testDeadLetter{err: errors.New("kafka down")}
+	h := ConsumerGroupHandler{
+		logger:      &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error { return errors.New("db error") },
+		deadLetter:  dlq,
+	}
+
+	err := h.ConsumeClaim(session, newTestClaim(
+		&sarama.ConsumerMessage{Topic: "orders", Offset: 3},
+		&sarama.ConsumerMessage{Topic: "orders", Offset: 4},
+	))
+	require.Error(t, err)
+	assert.Empty(t, session.marked)
+}
+
+func TestConsumeClaim_CancelledContextKeepsOffset(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	session := &testSession{ctx: ctx}
+	dlq := &testDeadLetter{}
+	h := ConsumerGroupHandler{
+		logger:      &testmock.TestLogger{},
+		handlerFunc: func(ctx context.Context, message []byte) error { return ctx.Err() },
+		deadLetter:  dlq,
+	}
+
+	err := h.ConsumeClaim(session, newTestClaim(&sarama.ConsumerMessage{Topic: "orders", Offset: 5}))
+	require.NoError(t, err)
+	assert.Empty(t, dlq.sent)
+	assert.Empty(t, session.marked)
+}
+
+func TestConsumer_PingTracksGroupMembership(t *testing.T) {
+	c := &Consumer{groupID: "order-consumer-group"}
+	h := ConsumerGroupHandler{member: &c.member}
+	session := &testSession{ctx: context.Background()}
+
+	assert.Error(t, c.Ping(context.Background()), "not joined yet")
+	require.NoError(t, h.Setup(session))
+	assert.NoError(t, c.Ping(context.Background()))
+	require.NoError(t, h.Cleanup(session))
+	assert.Error(t, c.Ping(context.Background()), "left during a rebalance")
+}
@@ -1,17 +1,22 @@
 package messagebrok
 
 import (
-	"log"
+	"context"
+	"sort"
+	"testberry/internal/ports"
 
 	"github.com/IBM/sarama"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Producer struct {
 	producer sarama.SyncProducer
 	topic    string
+	logger   ports.Logger
 }
 
-func NewProducer(brokers []string, topic string) (*Producer, error) {
+func NewProducer(brokers []string, topic string, logger ports.Logger) (*Producer, error) {
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 5
@@ -25,6 +30,7 @@ func NewProducer(brokers []string, topic string) (*Producer, error) {
 	return &Producer{
 		producer: prod,
 		topic:    topic,
+		logger:   logger,
 	}, nil
 }
 
@@ -32,18 +38,46 @@ func (p *Producer) Close() error {
 	return p.producer.Close()
 }
 
-func (p *Producer) Send(key string, value []byte) error {
+func (p *Producer) Send(ctx context.Context, key string, value []byte) error {
+	return p.SendWithHeaders(ctx, key, value, nil)
+}
+
+// SendWithHeaders produces a record in a span whose W3C trace context is added
+// to headers, so consumers continue the trace.
+func (p *Producer) SendWithHeaders(ctx context.Context, key string, value []byte, headers map[string]string) (err error) {
 	msg := &sarama.ProducerMessage{
-		Topic: p.topic,
-		Key:   sarama.StringEncoder(key),
-		Value: sarama.ByteEncoder(value),
+		Topic:   p.topic,
+		Key:     sarama.StringEncoder(key),
+		Value:   sarama.ByteEncoder(value),
+		Headers: recordHeaders(headers),
 	}
+	ctx, span := tracer.Start(ctx, "send "+p.topic, trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() { endSpan(span, err) }()
+	otel.GetTextMapPropagator().Inject(ctx, producerCarrier{msg: msg})
 
 	partition, offset, err := p.producer.SendMessage(msg)
 	if err != nil {
 		return err
 	}
+	span.SetAttributes(messagingAttributes(p.topic, partition, offset)...)
 
-	log.Printf("Message sent to Kafka partition %d at offset %d", partition, offset)
+	ports.LoggerFromContext(ctx, p.logger).Debug("Message sent", "topic", p.topic, "partition", partition, "offset", offset)
 	return nil
 }
+
+func recordHeaders(headers map[string]string) []sarama.RecordHeader {
+	if len(headers) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]sarama.RecordHeader, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, sarama.RecordHeader{Key: []byte(k), Value: []byte(headers[k])})
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package messagebrok
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	testmock "testberry/pkg/test"
+)
+
+var (
+	spansOnce sync.Once
+	spans     *tracetest.InMemoryExporter
+)
+
+// recordSpans installs, once per package run, a tracer provider recording to
+// an in-memory exporter, and empties it.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	spansOnce.Do(func() {
+		spans = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spans.Reset()
+	return spans
+}
+
+func TestTraceContext_PropagatesThroughKafkaHeaders(t *testing.T) {
+	recorded := recordSpans(t)
+
+	var produced *sarama.ProducerMessage
+	syncProducer := mocks.NewSyncProducer(t, nil)
+	syncProducer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		produced = msg
+		return nil
+	})
+	p := &Producer{producer: syncProducer, topic: "orders", logger: &testmock.TestLogger{}}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "SendRandomOrder")
+	require.NoError(t, p.SendWithHeaders(ctx, "order-key", []byte(`{}`), map[string]string{HeaderErrorClass: "x"}))
+	parent.End()
+	require.NotNil(t, produced)
+
+	carrier := producerCarrier{msg: produced}
+	assert.NotEmpty(t, carrier.Get("traceparent"))
+	assert.Equal(t, "x", carrier.Get(HeaderErrorClass), "caller headers are kept")
+
+	headers := make([]*sarama.RecordHeader, len(produced.Headers))
+	for i := range produced.Headers {
+		headers[i] = &produced.Headers[i]
+	}
+	var handled trace.SpanContext
+	h := ConsumerGroupHandler{logger: &testmock.TestLogger{}, handlerFunc: func(ctx context.Context, _ []byte) error {
+		handled = trace.SpanContextFromContext(ctx)
+		return nil
+	}}
+	msg := &sarama.ConsumerMessage{Topic: "orders", Offset: 1, Headers: headers, Value: []byte(`{}`)}
+	require.NoError(t, h.ConsumeClaim(&testSession{ctx: context.Background()}, newTestClaim(msg)))
+
+	assert.Equal(t, parent.SpanContext().TraceID(), handled.TraceID(), "the handler continues the producer's trace")
+	names := make([]string, 0, 3)
+	for _, s := range recorded.GetSpans() {
+		names = append(names, s.Name)
+	}
+	assert.ElementsMatch(t, []string{"SendRandomOrder", "send orders", "process orders"}, names)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+)
+
+// Cache counts the hits and misses of Get. The service reads the cache only
+// in GetOrder, so the counts are the hit ratio of order reads; event handling
+// gets what it needs from the repository instead.
+type Cache struct {
+	ports.Cache
+	metrics *Metrics
+}
+
+func NewCache(next ports.Cache, m *Metrics) *Cache {
+	return &Cache{Cache: next, metrics: m}
+}
+
+func (c *Cache) Get(ctx context.Context, orderUID string) (order_entity.Order, bool, error) {
+	order, found, err := c.Cache.Get(ctx, orderUID)
+	result := "miss"
+	switch {
+	case err != nil:
+		result = "error"
+	case found:
+		result = "hit"
+	}
+	c.metrics.cacheLookups.WithLabelValues(result).Inc()
+	return order, found, err
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"testberry/internal/ports"
+)
+
+// Consumer counts the messages its handler processes and fails, times the
+// handler and tracks each partition's lag.
+type Consumer struct {
+	next    ports.Consumer
+	metrics *Metrics
+}
+
+func NewConsumer(next ports.Consumer, m *Metrics) *Consumer {
+	return &Consumer{next: next, metrics: m}
+}
+
+func (c *Consumer) Consume(ctx context.Context, handler func(ctx context.Context, message []byte) error) error {
+	return c.next.Consume(ctx, func(ctx context.Context, message []byte) error {
+		c.metrics.messagesConsumed.Inc()
+		start := time.Now()
+		err := handler(ctx, message)
+		c.metrics.handlerDuration.Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			reason := ports.ErrorClassUnknown
+			var msgErr *ports.MessageError
+			if errors.As(err, &msgErr) {
+				reason = msgErr.Class
+			}
+			c.metrics.messagesFailed.WithLabelValues(reason).Inc()
+		} else {
+			c.metrics.messagesSucceeded.Inc()
+		}
+
+		if md, ok := ports.MessageFromContext(ctx); ok && md.HighWaterMark > 0 {
+			lag := max(md.HighWaterMark-md.Offset-1, 0)
+			c.metrics.consumerLag.WithLabelValues(md.Topic, strconv.FormatInt(int64(md.Partition), 10)).Set(float64(lag))
+		}
+		return err
+	})
+}
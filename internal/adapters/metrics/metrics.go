@@ -0,0 +1,98 @@
+// Package metrics instruments the service for Prometheus with decorators
+// around the ports and a middleware for HTTP routes.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "orders"
+
+// Metrics holds the collectors the decorators record to.
+type Metrics struct {
+	gatherer prometheus.Gatherer
+
+	messagesConsumed  prometheus.Counter
+	messagesSucceeded prometheus.Counter
+	messagesFailed    *prometheus.CounterVec
+	handlerDuration   prometheus.Histogram
+	consumerLag       *prometheus.GaugeVec
+
+	repoDuration *prometheus.HistogramVec
+	repoErrors   *prometheus.CounterVec
+
+	cacheLookups *prometheus.CounterVec
+
+	producerMessages *prometheus.CounterVec
+
+	httpDuration *prometheus.HistogramVec
+}
+
+// New registers every collector with reg, which also serves Handler.
+func New(reg *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		gatherer: reg,
+		messagesConsumed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_consumed_total",
+			Help: "Messages handed to the consumer handler.",
+		}),
+		messagesSucceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_succeeded_total",
+			Help: "Messages the handler processed.",
+		}),
+		messagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "messages_failed_total",
+			Help: "Messages the handler gave up on, by error class.",
+		}, []string{"reason"}),
+		handlerDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "handler_duration_seconds",
+			Help:    "Time the handler spent on a message, retries included.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "consumer", Name: "lag",
+			Help: "Records behind the partition's high-water mark after the last handled message.",
+		}, []string{"topic", "partition"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "repository", Name: "duration_seconds",
+			Help:    "Repository call latency, by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "repository", Name: "errors_total",
+			Help: "Failed repository calls, by operation. Unknown orders are not errors.",
+		}, []string{"operation"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "cache", Name: "lookups_total",
+			Help: "Order cache lookups, by result: hit, miss or error.",
+		}, []string{"result"}),
+		producerMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "producer", Name: "messages_total",
+			Help: "Messages sent, by producer and result: sent or failed.",
+		}, []string{"producer", "result"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help:    "HTTP request latency, by route, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+	}
+	reg.MustRegister(
+		m.messagesConsumed, m.messagesSucceeded, m.messagesFailed, m.handlerDuration, m.consumerLag,
+		m.repoDuration, m.repoErrors, m.cacheLookups, m.producerMessages, m.httpDuration,
+	)
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// InstrumentRoute records the latency of next under route, the pattern it is
+// registered with, so paths with IDs don't each get their own series.
+func (m *Metrics) InstrumentRoute(route string, next http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerDuration(m.httpDuration.MustCurryWith(prometheus.Labels{"route": route}), next)
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_RecordsLatencyAndErrors(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	repo := new(testmock.MockRepository)
+	ctx := context.Background()
+	repo.On("SaveOrder", ctx, testmock.Test_order).Return(errors.New("connection reset")).Once()
+	repo.On("GetOrderByID", ctx, "missing").Return(order_entity.Order{}, fmt.Errorf("%w: missing", ports.ErrOrderNotFound)).Once()
+
+	r := NewRepository(repo, m)
+	assert.Error(t, r.SaveOrder(ctx, testmock.Test_order))
+	_, err := r.GetOrderByID(ctx, "missing")
+	assert.ErrorIs(t, err, ports.ErrOrderNotFound)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repoErrors.WithLabelValues("save_order")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.repoErrors.WithLabelValues("get_order_by_id")), "unknown orders are not errors")
+	assert.Equal(t, 2, testutil.CollectAndCount(m.repoDuration))
+}
+
+func TestCache_CountsHitsAndMisses(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	cache := new(testmock.MockCache)
+	ctx := context.Background()
+	cache.On("Get", ctx, "hit").Return(testmock.Test_order, true, nil)
+	cache.On("Get", ctx, "miss").Return(order_entity.Order{}, false, nil)
+	cache.On("Get", ctx, "down").Return(order_entity.Order{}, false, errors.New("redis down"))
+
+	c := NewCache(cache, m)
+	for _, uid := range []string{"hit", "hit", "miss", "down"} {
+		_, _, _ = c.Get(ctx, uid)
+	}
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.cacheLookups.WithLabelValues("hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.cacheLookups.WithLabelValues("miss")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.cacheLookups.WithLabelValues("error")))
+}
+
+func TestConsumer_CountsMessagesAndLag(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	messages := []ports.MessageMetadata{
+		{Topic: "orders", Partition: 0, Offset: 10, HighWaterMark: 15},
+		{Topic: "orders", Partition: 0, Offset: 11, HighWaterMark: 15},
+		{Topic: "orders", Partition: 1, Offset: 3, HighWaterMark: 4},
+	}
+	consumer := &testmock.MockConsumer{ConsumeFunc: func(ctx context.Context, handler func(context.Context, []byte) error) error {
+		for _, md := range messages {
+			_ = handler(ports.ContextWithMessage(ctx, md), []byte(fmt.Sprint(md.Offset)))
+		}
+		return nil
+	}}
+
+	c := NewConsumer(consumer, m)
+	require.NoError(t, c.Consume(context.Background(), func(_ context.Context, message []byte) error {
+		switch string(message) {
+		case "11":
+			return &ports.MessageError{Class: ports.ErrorClassValidation, Err: errors.New("bad order")}
+		case "3":
+			return errors.New("boom")
+		}
+		return nil
+	}))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.messagesConsumed))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesSucceeded))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesFailed.WithLabelValues(ports.ErrorClassValidation)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.messagesFailed.WithLabelValues(ports.ErrorClassUnknown)))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.consumerLag.WithLabelValues("orders", "0")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.consumerLag.WithLabelValues("orders", "1")))
+}
+
+func TestProducer_CountsSends(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	producer := new(testmock.MockProducer)
+	producer.On("Send", mock.Anything, "ok", []byte("{}")).Return(nil)
+	producer.On("Send", mock.Anything, "fail", []byte("{}")).Return(errors.New("broker down"))
+
+	p := NewProducer(producer, "generator", m)
+	assert.NoError(t, p.Send(context.Background(), "ok", []byte("{}")))
+	assert.Error(t, p.Send(context.Background(), "fail", []byte("{}")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.producerMessages.WithLabelValues("generator", "sent")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.producerMessages.WithLabelValues("generator", "failed")))
+}
+
+func TestInstrumentRoute_ServedByHandler(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	h := m.InstrumentRoute("/order/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order/12345678901234567890", nil))
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `orders_http_request_duration_seconds_count{code="404",method="get",route="/order/"} 1`)
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"context"
+
+	"testberry/internal/ports"
+)
+
+// Producer counts the messages sent and failed under the producer's name.
+type Producer struct {
+	next    ports.Producer
+	name    string
+	metrics *Metrics
+}
+
+func NewProducer(next ports.Producer, name string, m *Metrics) *Producer {
+	return &Producer{next: next, name: name, metrics: m}
+}
+
+func (p *Producer) Send(ctx context.Context, key string, message []byte) error {
+	err := p.next.Send(ctx, key, message)
+	result := "sent"
+	if err != nil {
+		result = "failed"
+	}
+	p.metrics.producerMessages.WithLabelValues(p.name, result).Inc()
+	return err
+}
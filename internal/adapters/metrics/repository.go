@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+)
+
+// Repository records the latency and errors of SaveOrder and GetOrderByID
+// and passes every other call through.
+type Repository struct {
+	ports.Repository
+	metrics *Metrics
+}
+
+func NewRepository(next ports.Repository, m *Metrics) *Repository {
+	return &Repository{Repository: next, metrics: m}
+}
+
+func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) error {
+	start := time.Now()
+	err := r.Repository.SaveOrder(ctx, order)
+	r.record("save_order", start, err)
+	return err
+}
+
+func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_entity.Order, error) {
+	start := time.Now()
+	order, err := r.Repository.GetOrderByID(ctx, orderUID)
+	r.record("get_order_by_id", start, err)
+	return order, err
+}
+
+func (r *Repository) record(operation string, start time.Time, err error) {
+	r.metrics.repoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, ports.ErrOrderNotFound) {
+		r.metrics.repoErrors.WithLabelValues(operation).Inc()
+	}
+}
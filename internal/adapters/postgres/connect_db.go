@@ -2,7 +2,7 @@ package postgres
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
 )
 
 func ConnectDB(connStr string) (*sql.DB, error) {
@@ -12,12 +12,7 @@ func ConnectDB(connStr string) (*sql.DB, error) {
 	}
 
 	if err := db.Ping(); err != nil {
-		defer func() {
-			if err := db.Close(); err != nil {
-				fmt.Println("failed to close db", err)
-			}
-		}()
-		return nil, err
+		return nil, errors.Join(err, db.Close())
 	}
 
 	return db, nil
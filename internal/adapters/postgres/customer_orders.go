@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testberry/internal/ports"
+)
+
+// ListCustomerOrders reads summaries straight from orders, payment and
+// delivery, counting items instead of loading them. It walks
+// orders_customer_created_idx newest first.
+func (r *Repository) ListCustomerOrders(ctx context.Context, query ports.CustomerOrdersQuery) (ports.CustomerOrdersPage, error) {
+	args := []interface{}{query.CustomerID}
+	where := "o.customer_id = $1"
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, ports.SortCreatedDesc)
+		if err != nil {
+			return ports.CustomerOrdersPage{}, err
+		}
+		args = append(args, after.DateCreated, after.OrderUID)
+		where += " AND (o.date_created, o.order_uid) < ($2, $3)"
+	}
+	// One extra row tells whether there is a next page.
+	args = append(args, query.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT o.order_uid, o.date_created, p.amount, p.currency, d.city,
+			(SELECT count(*) FROM item i WHERE i.order_uid = o.order_uid)
+		FROM orders o
+		JOIN payment p ON o.payment_id = p.id
+		JOIN delivery d ON o.delivery_id = d.id
+		WHERE %s
+		ORDER BY o.date_created DESC, o.order_uid DESC
+		LIMIT $%d`, where, len(args)), args...)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to query customer orders", "err", err)
+		return ports.CustomerOrdersPage{}, classifyError(err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			r.log(ctx).Error("failed to close rows", "err", err)
+		}
+	}()
+
+	summaries := make([]ports.OrderSummary, 0, query.Limit+1)
+	for rows.Next() {
+		var s ports.OrderSummary
+		if err := rows.Scan(&s.OrderUID, &s.DateCreated, &s.TotalAmount, &s.Currency, &s.DeliveryCity, &s.ItemCount); err != nil {
+			return ports.CustomerOrdersPage{}, classifyError(err)
+		}
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		return ports.CustomerOrdersPage{}, classifyError(err)
+	}
+
+	page := ports.CustomerOrdersPage{Orders: summaries}
+	if len(summaries) > query.Limit {
+		page.Orders = summaries[:query.Limit]
+		last := page.Orders[len(page.Orders)-1]
+		page.NextCursor = encodeCursor(listCursor{Sort: ports.SortCreatedDesc, DateCreated: last.DateCreated, OrderUID: last.OrderUID})
+	}
+	return page, nil
+}
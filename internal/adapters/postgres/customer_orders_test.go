@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_ListCustomerOrders(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		order := testmock.Test_order
+		order.OrderUID = fmt.Sprintf("history-order-%d", i)
+		order.DateCreated = base.Add(time.Duration(i) * time.Hour)
+		order.CustomerID = "customer-a"
+		order.Payment.Amount = 100 * (i + 1)
+		order.Items = nil
+		for j := 0; j <= i; j++ {
+			order.Items = append(order.Items, testmock.Test_order.Items[0])
+		}
+		require.NoError(t, repo.SaveOrder(ctx, order))
+	}
+	other := testmock.Test_order
+	other.OrderUID = "history-order-other"
+	other.CustomerID = "customer-b"
+	require.NoError(t, repo.SaveOrder(ctx, other))
+
+	page, err := repo.ListCustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "customer-a", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Orders, 2)
+	assert.Equal(t, "history-order-2", page.Orders[0].OrderUID)
+	assert.Equal(t, 300, page.Orders[0].TotalAmount)
+	assert.Equal(t, testmock.Test_order.Payment.Currency, page.Orders[0].Currency)
+	assert.Equal(t, 3, page.Orders[0].ItemCount)
+	assert.Equal(t, testmock.Test_order.Delivery.City, page.Orders[0].DeliveryCity)
+	assert.Equal(t, "history-order-1", page.Orders[1].OrderUID)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = repo.ListCustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "customer-a", Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Orders, 1)
+	assert.Equal(t, "history-order-0", page.Orders[0].OrderUID)
+	assert.Equal(t, 1, page.Orders[0].ItemCount)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = repo.ListCustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "nobody", Limit: 2})
+	require.NoError(t, err)
+	assert.Empty(t, page.Orders)
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testberry/internal/ports"
+
+	"github.com/lib/pq"
+)
+
+// transientClasses are SQLSTATE classes worth retrying: connection exceptions,
+// serialization failures and deadlocks, insufficient resources and operator intervention.
+var transientClasses = map[pq.ErrorClass]bool{
+	"08": true,
+	"40": true,
+	"53": true,
+	"57": true,
+}
+
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ports.ErrTransient) {
+		return err
+	}
+	if isTransient(err) {
+		cause := ports.ErrDependencyUnavailable
+		if isTimeout(err) {
+			cause = ports.ErrTimeout
+		}
+		return fmt.Errorf("%w: %w: %w", ports.ErrTransient, cause, err)
+	}
+	return err
+}
+
+// queryCanceled is the SQLSTATE Postgres reports when statement_timeout fires.
+const queryCanceled pq.ErrorCode = "57014"
+
+func isTimeout(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == queryCanceled
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+func isTransient(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientClasses[pqErr.Code.Class()]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
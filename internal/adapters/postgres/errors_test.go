@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"testberry/internal/ports"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		transient   bool
+		unavailable bool
+		timeout     bool
+	}{
+		{name: "bad connection", err: driver.ErrBadConn, transient: true, unavailable: true},
+		{name: "serialization failure", err: &pq.Error{Code: "40001"}, transient: true, unavailable: true},
+		{name: "statement timeout", err: &pq.Error{Code: "57014"}, transient: true, timeout: true},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, transient: true, timeout: true},
+		{name: "unique violation", err: &pq.Error{Code: "23505"}},
+		{name: "plain error", err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.transient, errors.Is(err, ports.ErrTransient))
+			assert.Equal(t, tt.unavailable, errors.Is(err, ports.ErrDependencyUnavailable))
+			assert.Equal(t, tt.timeout, errors.Is(err, ports.ErrTimeout))
+		})
+	}
+	assert.NoError(t, classifyError(nil))
+}
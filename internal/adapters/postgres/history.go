@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	"time"
+)
+
+// recordHistory appends the order as it is after a write to order_history,
+// together with the topic, partition and offset of the Kafka record the write
+// came from, as found in ctx. Writes made outside the consumer have no source.
+// snapshot is nil when the order was deleted.
+func (r *Repository) recordHistory(ctx context.Context, tx *sql.Tx, orderUID string, op order_entity.EventType, snapshot *order_entity.Order) error {
+	var payload sql.NullString
+	if snapshot != nil {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		payload = sql.NullString{String: string(data), Valid: true}
+	}
+	var (
+		topic     sql.NullString
+		partition sql.NullInt32
+		offset    sql.NullInt64
+	)
+	if md, ok := ports.MessageFromContext(ctx); ok {
+		topic = sql.NullString{String: md.Topic, Valid: true}
+		partition = sql.NullInt32{Int32: md.Partition, Valid: true}
+		offset = sql.NullInt64{Int64: md.Offset, Valid: true}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO order_history (order_uid, operation, snapshot, source_topic, source_partition, source_offset)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		orderUID, string(op), payload, topic, partition, offset,
+	); err != nil {
+		r.log(ctx).Error("Repo: Failed to record order history", "err", err)
+		return classifyError(err)
+	}
+	return nil
+}
+
+// GetOrderAsOf returns the order as it was stored at the given time. Orders
+// that didn't exist yet or had been deleted by then are not found. Orders
+// stored before the history was introduced are known from the migration on.
+func (r *Repository) GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error) {
+	var snapshot sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT snapshot FROM order_history
+		WHERE order_uid = $1 AND recorded_at <= $2
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT 1`, orderUID, at,
+	).Scan(&snapshot)
+	if err == sql.ErrNoRows || (err == nil && !snapshot.Valid) {
+		return order_entity.Order{}, fmt.Errorf("%w: %s as of %s", ports.ErrOrderNotFound, orderUID, at.Format(time.RFC3339))
+	}
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to read order history", "err", err)
+		return order_entity.Order{}, classifyError(err)
+	}
+
+	var order order_entity.Order
+	if err := json.Unmarshal([]byte(snapshot.String), &order); err != nil {
+		return order_entity.Order{}, err
+	}
+	return order, nil
+}
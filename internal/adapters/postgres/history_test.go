@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_OrderHistory(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictOverwrite))
+	ctx := ports.ContextWithMessage(context.Background(), ports.MessageMetadata{Topic: "orders", Partition: 1, Offset: 42})
+	uid := testmock.Test_order.OrderUID
+
+	before := time.Now()
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	afterCreate := time.Now()
+
+	changed := testmock.Test_order
+	changed.Delivery.City = "Haifa"
+	_, err := repo.UpdateOrder(context.Background(), changed)
+	require.NoError(t, err)
+	afterUpdate := time.Now()
+
+	require.NoError(t, repo.UpdateOrderStatus(ctx, uid, order_entity.StatusPaid))
+	afterPaid := time.Now()
+	_, err = repo.DeleteOrder(ctx, uid)
+	require.NoError(t, err)
+
+	_, err = repo.GetOrderAsOf(ctx, uid, before.Add(-time.Second))
+	require.ErrorIs(t, err, ports.ErrOrderNotFound)
+
+	order, err := repo.GetOrderAsOf(ctx, uid, afterCreate)
+	require.NoError(t, err)
+	assert.Equal(t, testmock.Test_order.Delivery.City, order.Delivery.City)
+	assert.Equal(t, order_entity.StatusCreated, order.Status)
+
+	order, err = repo.GetOrderAsOf(ctx, uid, afterUpdate)
+	require.NoError(t, err)
+	assert.Equal(t, "Haifa", order.Delivery.City)
+
+	order, err = repo.GetOrderAsOf(ctx, uid, afterPaid)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.StatusPaid, order.Status)
+	assert.Len(t, order.Items, len(testmock.Test_order.Items))
+
+	_, err = repo.GetOrderAsOf(ctx, uid, time.Now())
+	require.ErrorIs(t, err, ports.ErrOrderNotFound, "deleted orders are not found")
+
+	var topics, unsourced int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FILTER (WHERE source_topic = 'orders' AND source_offset = 42),
+		count(*) FILTER (WHERE source_topic IS NULL) FROM order_history`).Scan(&topics, &unsourced))
+	assert.Equal(t, 3, topics)
+	assert.Equal(t, 1, unsourced, "writes outside the consumer have no source")
+
+	_, err = db.Exec(`DELETE FROM order_history`)
+	require.Error(t, err, "order_history is append-only")
+}
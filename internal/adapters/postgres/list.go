@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testberry/internal/ports"
+	"time"
+)
+
+// listCursor is the keyset position after the last order of a page. It is
+// handed to clients base64-encoded and carries the sort it was made for.
+type listCursor struct {
+	Sort        ports.OrderSort `json:"s"`
+	DateCreated time.Time       `json:"d"`
+	OrderUID    string          `json:"u"`
+}
+
+func encodeCursor(c listCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, sort ports.OrderSort) (listCursor, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ports.ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.OrderUID == "" {
+		return c, fmt.Errorf("%w: malformed cursor", ports.ErrInvalidQuery)
+	}
+	if c.Sort != sort {
+		return c, fmt.Errorf("%w: cursor was issued for sort %q", ports.ErrInvalidQuery, c.Sort)
+	}
+	return c, nil
+}
+
+func (r *Repository) ListOrders(ctx context.Context, query ports.OrderQuery) (ports.OrderPage, error) {
+	conds, args := filterConditions(query.Filter)
+
+	cmp, orderBy := "<", "o.date_created DESC, o.order_uid DESC"
+	if query.Sort == ports.SortCreatedAsc {
+		cmp, orderBy = ">", "o.date_created ASC, o.order_uid ASC"
+	}
+	if query.Cursor != "" {
+		after, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return ports.OrderPage{}, err
+		}
+		args = append(args, after.DateCreated, after.OrderUID)
+		conds = append(conds, fmt.Sprintf("(o.date_created, o.order_uid) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+
+	// One extra row tells whether there is a next page.
+	orders, err := r.queryOrders(ctx, conds, args, orderBy, query.Limit+1)
+	if err != nil {
+		return ports.OrderPage{}, err
+	}
+	page := ports.OrderPage{Orders: orders}
+	if len(orders) > query.Limit {
+		page.Orders = orders[:query.Limit]
+		last := page.Orders[len(page.Orders)-1]
+		page.NextCursor = encodeCursor(listCursor{Sort: query.Sort, DateCreated: last.DateCreated, OrderUID: last.OrderUID})
+	}
+	if len(page.Orders) > 0 {
+		if err := r.loadItems(ctx, page.Orders); err != nil {
+			return ports.OrderPage{}, err
+		}
+	}
+	return page, nil
+}
+
+func filterConditions(f ports.OrderFilter) ([]string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.CustomerID != "" {
+		add("o.customer_id = $%d", f.CustomerID)
+	}
+	if f.TrackNumber != "" {
+		add("o.track_number = $%d", f.TrackNumber)
+	}
+	if f.DeliveryService != "" {
+		add("o.delivery_service = $%d", f.DeliveryService)
+	}
+	if f.PaymentProvider != "" {
+		add("p.provider = $%d", f.PaymentProvider)
+	}
+	if f.PaymentBank != "" {
+		add("p.bank = $%d", f.PaymentBank)
+	}
+	// date_created is a TIMESTAMP holding UTC, and casting a bound to it would
+	// drop the offset rather than convert it.
+	if !f.CreatedFrom.IsZero() {
+		add("o.date_created >= $%d", f.CreatedFrom.UTC())
+	}
+	if !f.CreatedTo.IsZero() {
+		add("o.date_created < $%d", f.CreatedTo.UTC())
+	}
+
+	var itemConds []string
+	if f.ItemBrand != "" {
+		args = append(args, f.ItemBrand)
+		itemConds = append(itemConds, fmt.Sprintf("i.brand = $%d", len(args)))
+	}
+	if f.ItemNmID != 0 {
+		args = append(args, f.ItemNmID)
+		itemConds = append(itemConds, fmt.Sprintf("i.nm_id = $%d", len(args)))
+	}
+	if len(itemConds) > 0 {
+		conds = append(conds, "EXISTS (SELECT 1 FROM item i WHERE i.order_uid = o.order_uid AND "+strings.Join(itemConds, " AND ")+")")
+	}
+	return conds, args
+}
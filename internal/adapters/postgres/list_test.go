@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	c := listCursor{Sort: ports.SortCreatedDesc, DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 1000, time.UTC), OrderUID: "a"}
+	got, err := decodeCursor(encodeCursor(c), ports.SortCreatedDesc)
+	require.NoError(t, err)
+	assert.True(t, c.DateCreated.Equal(got.DateCreated))
+	assert.Equal(t, c.OrderUID, got.OrderUID)
+
+	_, err = decodeCursor(encodeCursor(c), ports.SortCreatedAsc)
+	assert.ErrorIs(t, err, ports.ErrInvalidQuery)
+	_, err = decodeCursor("not a cursor", ports.SortCreatedDesc)
+	assert.ErrorIs(t, err, ports.ErrInvalidQuery)
+}
+
+func TestRepository_ListOrders(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		order := testmock.Test_order
+		order.OrderUID = fmt.Sprintf("list-order-%d", i)
+		order.DateCreated = base.Add(time.Duration(i) * time.Hour)
+		order.CustomerID = "customer-a"
+		if i%2 == 1 {
+			order.CustomerID = "customer-b"
+			order.Payment.Bank = "sber"
+		}
+		require.NoError(t, repo.SaveOrder(ctx, order))
+	}
+
+	uids := func(page ports.OrderPage) []string {
+		var out []string
+		for _, o := range page.Orders {
+			out = append(out, o.OrderUID)
+		}
+		return out
+	}
+
+	page, err := repo.ListOrders(ctx, ports.OrderQuery{Sort: ports.SortCreatedDesc, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-4", "list-order-3"}, uids(page))
+	assert.Len(t, page.Orders[0].Items, len(testmock.Test_order.Items))
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{Sort: ports.SortCreatedDesc, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-2", "list-order-1"}, uids(page))
+
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{Sort: ports.SortCreatedDesc, Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-0"}, uids(page))
+	assert.Empty(t, page.NextCursor)
+
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{
+		Filter: ports.OrderFilter{CustomerID: "customer-b", PaymentBank: "sber"},
+		Sort:   ports.SortCreatedAsc,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-1", "list-order-3"}, uids(page))
+
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{
+		Filter: ports.OrderFilter{
+			ItemBrand:   testmock.Test_order.Items[0].Brand,
+			ItemNmID:    testmock.Test_order.Items[0].NmID,
+			CreatedFrom: base.Add(time.Hour),
+			CreatedTo:   base.Add(3 * time.Hour),
+		},
+		Sort:  ports.SortCreatedDesc,
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-2", "list-order-1"}, uids(page))
+
+	// Bounds with an offset are compared as instants: 19:00+03:00 is 16:00 UTC,
+	// not 19:00, so list-order-4 matches.
+	msk := time.FixedZone("MSK", 3*60*60)
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{
+		Filter: ports.OrderFilter{CreatedFrom: base.Add(4 * time.Hour).In(msk)},
+		Sort:   ports.SortCreatedAsc,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"list-order-4"}, uids(page))
+
+	page, err = repo.ListOrders(ctx, ports.OrderQuery{Filter: ports.OrderFilter{ItemNmID: 1}, Sort: ports.SortCreatedDesc, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page.Orders)
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testberry/internal/ports"
+
+	"github.com/lib/pq"
+)
+
+// lookupSource is one place an order can be matched by: a FROM clause joined
+// to orders o and filtered on $1.
+type lookupSource struct {
+	matchedBy string
+	from      string
+}
+
+var (
+	trackNumberSources = []lookupSource{
+		{ports.MatchOrderTrackNumber, "orders o WHERE o.track_number = $1"},
+		{ports.MatchItemTrackNumber, "item i JOIN orders o ON o.order_uid = i.order_uid WHERE i.track_number = $1"},
+	}
+	paymentIDSources = []lookupSource{
+		{ports.MatchPaymentTransaction, "payment p JOIN orders o ON o.payment_id = p.id WHERE p.transaction = $1"},
+		{ports.MatchPaymentRequestID, "payment p JOIN orders o ON o.payment_id = p.id WHERE p.request_id = $1"},
+	}
+)
+
+func (r *Repository) FindByTrackNumber(ctx context.Context, trackNumber string, limit int) ([]ports.OrderMatch, error) {
+	return r.lookupOrders(ctx, trackNumberSources, trackNumber, limit)
+}
+
+func (r *Repository) FindByPaymentID(ctx context.Context, paymentID string, limit int) ([]ports.OrderMatch, error) {
+	return r.lookupOrders(ctx, paymentIDSources, paymentID, limit)
+}
+
+// lookupOrders matches value in every source separately, so each can use its
+// own index, and merges the matches per order.
+func (r *Repository) lookupOrders(ctx context.Context, sources []lookupSource, value string, limit int) ([]ports.OrderMatch, error) {
+	selects := make([]string, len(sources))
+	for i, src := range sources {
+		selects[i] = fmt.Sprintf("SELECT o.order_uid, o.date_created, '%s' AS matched_by FROM %s", src.matchedBy, src.from)
+	}
+	query := `
+		SELECT m.order_uid, m.date_created, array_agg(DISTINCT m.matched_by)
+		FROM (` + strings.Join(selects, "\n\t\tUNION ALL ") + `) m
+		GROUP BY m.order_uid, m.date_created
+		ORDER BY m.date_created DESC, m.order_uid DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, value, limit)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to look up orders", "err", err)
+		return nil, classifyError(err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			r.log(ctx).Error("failed to close rows", "err", err)
+		}
+	}()
+
+	var matches []ports.OrderMatch
+	for rows.Next() {
+		var m ports.OrderMatch
+		if err := rows.Scan(&m.OrderUID, &m.DateCreated, pq.Array(&m.MatchedBy)); err != nil {
+			return nil, classifyError(err)
+		}
+		matches = append(matches, m)
+	}
+	return matches, classifyError(rows.Err())
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_FindByTrackNumber(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	order := testmock.Test_order
+	order.OrderUID = "lookup-order-0"
+	order.DateCreated = base
+	require.NoError(t, repo.SaveOrder(ctx, order))
+
+	// A second order whose item, but not the order itself, carries the track.
+	split := testmock.Test_order
+	split.OrderUID = "lookup-order-1"
+	split.TrackNumber = "WBILMOTHERTRACK"
+	split.DateCreated = base.Add(time.Hour)
+	require.NoError(t, repo.SaveOrder(ctx, split))
+
+	matches, err := repo.FindByTrackNumber(ctx, testmock.Test_order.TrackNumber, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, "lookup-order-1", matches[0].OrderUID)
+	assert.Equal(t, []string{ports.MatchItemTrackNumber}, matches[0].MatchedBy)
+	assert.Equal(t, "lookup-order-0", matches[1].OrderUID)
+	assert.ElementsMatch(t, []string{ports.MatchOrderTrackNumber, ports.MatchItemTrackNumber}, matches[1].MatchedBy)
+
+	matches, err = repo.FindByTrackNumber(ctx, testmock.Test_order.TrackNumber, 1)
+	require.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	matches, err = repo.FindByTrackNumber(ctx, "unknown", 10)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestRepository_FindByPaymentID(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+
+	matches, err := repo.FindByPaymentID(ctx, testmock.Test_order.Payment.Transaction, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, testmock.Test_order.OrderUID, matches[0].OrderUID)
+	assert.Equal(t, []string{ports.MatchPaymentTransaction}, matches[0].MatchedBy)
+
+	matches, err = repo.FindByPaymentID(ctx, testmock.Test_order.Payment.RequestID, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, []string{ports.MatchPaymentRequestID}, matches[0].MatchedBy)
+}
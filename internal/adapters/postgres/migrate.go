@@ -0,0 +1,351 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testberry/internal/ports"
+)
+
+var (
+	ErrDirtyMigration   = errors.New("database is dirty")
+	ErrUnknownMigration = errors.New("unknown migration")
+	ErrMigrationCheck   = errors.New("existing rows violate the migration")
+)
+
+// migrationLockID is the key of the advisory lock held while migrating, so
+// replicas starting together apply each migration once.
+const migrationLockID int64 = 0x6f72646572730001
+
+// schema_migrations has the layout migrate/migrate uses, so databases it has
+// migrated carry on where it stopped.
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	dirty BOOLEAN NOT NULL
+)`
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+)\.(up|down|check)\.sql$`)
+
+// A migration may come with a NNNN_name.check.sql query returning
+// (violation, row_count) pairs: the rows that would break it. Up refuses to
+// apply the migration while any count is above zero.
+type migration struct {
+	version         int64
+	name            string
+	up, down, check string
+}
+
+// CheckViolation counts the rows that keep a pending migration from applying.
+type CheckViolation struct {
+	Version   int64
+	Name      string
+	Violation string
+	Rows      int64
+}
+
+// MigrationStatus reports whether a migration is applied. Dirty marks the
+// current version when a migration failed half-way outside a transaction.
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	Applied bool
+	Dirty   bool
+}
+
+// Migrator applies the versioned migrations of a file system, each in its own
+// transaction, and records the current version in schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	logger     ports.Logger
+	migrations []migration
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS, logger ports.Logger) (*Migrator, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*migration)
+	for _, name := range names {
+		parts := migrationFile.FindStringSubmatch(name)
+		if parts == nil {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name.up.sql, .down.sql or .check.sql", name)
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", name)
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: parts[2]}
+			byVersion[version] = m
+		} else if m.name != parts[2] {
+			return nil, fmt.Errorf("migration %s: version %d is also named %s", name, version, m.name)
+		}
+		switch parts[3] {
+		case "up":
+			m.up = string(body)
+		case "down":
+			m.down = string(body)
+		case "check":
+			m.check = string(body)
+		}
+	}
+
+	r := &Migrator{db: db, logger: logger}
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.version, m.name)
+		}
+		r.migrations = append(r.migrations, *m)
+	}
+	sort.Slice(r.migrations, func(i, j int) bool { return r.migrations[i].version < r.migrations[j].version })
+	return r, nil
+}
+
+// Up applies every migration newer than the current version and returns how
+// many it applied.
+func (r *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		current, err := r.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if m.version <= current {
+				continue
+			}
+			violations, err := r.runCheck(ctx, conn, m)
+			if err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return checkError(violations)
+			}
+			if err := r.apply(ctx, conn, m.up, m.version); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", m.version, m.name, err)
+			}
+			r.logger.Info("Applied migration", "version", m.version, "migration", m.name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations.
+func (r *Migrator) Down(ctx context.Context, steps int) error {
+	return r.locked(ctx, func(conn *sql.Conn) error {
+		current, err := r.cleanVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for ; steps > 0 && current > 0; steps-- {
+			i := r.index(current)
+			if i < 0 {
+				return fmt.Errorf("%w: database is at version %d", ErrUnknownMigration, current)
+			}
+			m := r.migrations[i]
+			if m.down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", m.version, m.name)
+			}
+			var previous int64
+			if i > 0 {
+				previous = r.migrations[i-1].version
+			}
+			if err := r.apply(ctx, conn, m.down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", m.version, m.name, err)
+			}
+			r.logger.Info("Reverted migration", "version", m.version, "migration", m.name)
+			current = previous
+		}
+		return nil
+	})
+}
+
+// Status lists every known migration, oldest first.
+func (r *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		current, dirty, err := r.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			statuses = append(statuses, MigrationStatus{
+				Version: m.version,
+				Name:    m.name,
+				Applied: m.version <= current,
+				Dirty:   dirty && m.version == current,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Check runs the checks of every pending migration without applying anything.
+// The checks of later migrations run against the current schema, not the one
+// the earlier pending migrations would leave.
+func (r *Migrator) Check(ctx context.Context) ([]CheckViolation, error) {
+	var violations []CheckViolation
+	err := r.locked(ctx, func(conn *sql.Conn) error {
+		current, _, err := r.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			if m.version <= current {
+				continue
+			}
+			found, err := r.runCheck(ctx, conn, m)
+			if err != nil {
+				return err
+			}
+			violations = append(violations, found...)
+		}
+		return nil
+	})
+	return violations, err
+}
+
+// Force records version as applied and clean without running anything, to
+// recover from a dirty database or adopt one created by hand. Version 0
+// marks no migration as applied.
+func (r *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && r.index(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownMigration, version)
+	}
+	return r.locked(ctx, func(conn *sql.Conn) error {
+		return setMigrationVersion(ctx, conn, version)
+	})
+}
+
+func (r *Migrator) index(version int64) int {
+	for i, m := range r.migrations {
+		if m.version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// locked runs fn on a single connection holding the migration advisory lock.
+func (r *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// The session lock must be released even if ctx is done, or the pooled
+		// connection would keep holding it.
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			r.logger.Error("failed to release migration lock", "err", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (r *Migrator) version(ctx context.Context, conn *sql.Conn) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+func (r *Migrator) cleanVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	version, dirty, err := r.version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d: fix the schema and force a version", ErrDirtyMigration, version)
+	}
+	return version, nil
+}
+
+// apply runs a migration script and moves schema_migrations to version in one
+// transaction.
+func (r *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := setMigrationVersion(ctx, tx, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Migrator) runCheck(ctx context.Context, conn *sql.Conn, m migration) ([]CheckViolation, error) {
+	if m.check == "" {
+		return nil, nil
+	}
+	rows, err := conn.QueryContext(ctx, m.check)
+	if err != nil {
+		return nil, fmt.Errorf("migration %d_%s check: %w", m.version, m.name, err)
+	}
+	defer rows.Close()
+
+	var violations []CheckViolation
+	for rows.Next() {
+		v := CheckViolation{Version: m.version, Name: m.name}
+		if err := rows.Scan(&v.Violation, &v.Rows); err != nil {
+			return nil, fmt.Errorf("migration %d_%s check: %w", m.version, m.name, err)
+		}
+		if v.Rows > 0 {
+			violations = append(violations, v)
+		}
+	}
+	return violations, rows.Err()
+}
+
+func checkError(violations []CheckViolation) error {
+	msgs := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = fmt.Sprintf("%s (%d rows)", v.Violation, v.Rows)
+	}
+	v := violations[0]
+	return fmt.Errorf("%w %d_%s: %s", ErrMigrationCheck, v.Version, v.Name, strings.Join(msgs, "; "))
+}
+
+func setMigrationVersion(ctx context.Context, q querier, version int64) error {
+	if _, err := q.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version == 0 {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version)
+	return err
+}
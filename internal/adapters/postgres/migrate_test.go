@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"testberry/deployments/deployments/migrations"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMigrator_ParsesFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_second.up.sql":    {Data: []byte("CREATE TABLE b ()")},
+		"0001_first.up.sql":     {Data: []byte("CREATE TABLE a ()")},
+		"0001_first.down.sql":   {Data: []byte("DROP TABLE a")},
+		"0002_second.down.sql":  {Data: []byte("DROP TABLE b")},
+		"0002_second.check.sql": {Data: []byte("SELECT 'a has rows', count(*) FROM a")},
+	}
+	m, err := NewMigrator(nil, fsys, &testmock.TestLogger{})
+	require.NoError(t, err)
+	require.Len(t, m.migrations, 2)
+	assert.Equal(t, int64(1), m.migrations[0].version)
+	assert.Equal(t, "first", m.migrations[0].name)
+	assert.Equal(t, "DROP TABLE b", m.migrations[1].down)
+	assert.NotEmpty(t, m.migrations[1].check)
+
+	for name, file := range map[string]string{
+		"no up file":   "0003_third.down.sql",
+		"bad name":     "third.up.sql",
+		"zero version": "0000_zero.up.sql",
+	} {
+		bad := fstest.MapFS{file: {Data: []byte("SELECT 1")}}
+		_, err := NewMigrator(nil, bad, &testmock.TestLogger{})
+		assert.Error(t, err, name)
+	}
+
+	_, err = NewMigrator(nil, migrations.FS, &testmock.TestLogger{})
+	assert.NoError(t, err, "embedded migrations")
+}
+
+func TestMigrator_DownUpAndForce(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	m, err := NewMigrator(db, migrations.FS, &testmock.TestLogger{})
+	require.NoError(t, err)
+	last := m.migrations[len(m.migrations)-1].version
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied, "testDB already applied every migration")
+
+	require.NoError(t, m.Down(ctx, 1))
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+	assert.True(t, statuses[len(statuses)-2].Applied)
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, applied)
+
+	_, err = db.Exec(`UPDATE schema_migrations SET dirty = true`)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.ErrorIs(t, err, ErrDirtyMigration)
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[len(statuses)-1].Dirty)
+
+	require.ErrorIs(t, m.Force(ctx, 999), ErrUnknownMigration)
+	require.NoError(t, m.Force(ctx, last))
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+}
+
+func TestSchemaHardening(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewRepository(db, &testmock.TestLogger{})
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+
+	_, err := db.Exec(`UPDATE payment SET amount = -1`)
+	require.Error(t, err, "amounts are non-negative")
+	_, err = db.Exec(`UPDATE payment SET currency = 'US'`)
+	require.Error(t, err, "currencies have 3 letters")
+
+	// Deleting an order by hand takes its items, delivery and payment along.
+	_, err = db.Exec(`DELETE FROM orders`)
+	require.NoError(t, err)
+	assertRowCounts(t, db, 0, 0, 0, 0)
+}
+
+func TestMigrator_CheckBlocksUp(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	m, err := NewMigrator(db, migrations.FS, &testmock.TestLogger{})
+	require.NoError(t, err)
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+
+	// Revert down to just before 0010_schema_hardening.
+	var hardening int64 = 10
+	steps := 0
+	for _, s := range statuses {
+		if s.Version >= hardening {
+			steps++
+		}
+	}
+	require.NoError(t, m.Down(ctx, steps))
+	t.Cleanup(func() {
+		_, _ = db.Exec(`TRUNCATE payment RESTART IDENTITY CASCADE`)
+		_, err := m.Up(ctx)
+		require.NoError(t, err)
+	})
+	_, err = db.Exec(`INSERT INTO payment (currency, amount) VALUES ('USD', -5)`)
+	require.NoError(t, err)
+
+	violations, err := m.Check(ctx)
+	require.NoError(t, err)
+	var found []string
+	for _, v := range violations {
+		assert.Equal(t, hardening, v.Version)
+		assert.Equal(t, int64(1), v.Rows)
+		found = append(found, v.Violation)
+	}
+	assert.ElementsMatch(t, []string{"payment: NULL column", "payment_amount_check: amount >= 0"}, found)
+
+	_, err = m.Up(ctx)
+	require.ErrorIs(t, err, ErrMigrationCheck)
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.False(t, statuses[len(statuses)-1].Applied)
+}
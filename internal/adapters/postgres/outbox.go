@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// enqueueEvent records an order event in the outbox as part of tx, so it is
+// published if and only if the change it describes is committed.
+func (r *Repository) enqueueEvent(ctx context.Context, tx *sql.Tx, event order_entity.Event) error {
+	event.Version = order_entity.EventVersion
+	occurredAt := time.Now().UTC()
+	event.OccurredAt = &occurredAt
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO outbox (aggregate_id, event_type, payload) VALUES ($1, $2, $3)`,
+		event.OrderUID, string(event.Type), string(payload),
+	); err != nil {
+		r.log(ctx).Error("Repo: Failed to enqueue outbox event", "err", err)
+		return classifyError(err)
+	}
+	return nil
+}
+
+// outboxClaimLease is how long claimed messages are reserved for the relay
+// that claimed them. A relay that dies mid-batch leaves them to be claimed
+// again once it runs out.
+const outboxClaimLease = time.Minute
+
+// RelayOutbox claims a batch, publishes it outside any transaction and then
+// marks what was sent as published, so a slow broker holds neither a
+// connection nor a lock. Claims are taken under an advisory lock and skip
+// orders with an earlier message claimed by another relay, which keeps
+// per-order ordering across several replicas. Another relay holding the lock
+// is not an error: nothing is relayed.
+func (r *Repository) RelayOutbox(ctx context.Context, limit int, publish func(ports.OutboxMessage) error) (int, error) {
+	messages, err := r.claimOutbox(ctx, limit)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	failed := make(map[string]bool)
+	var sent, unsent []int64
+	for _, msg := range messages {
+		if failed[msg.OrderUID] {
+			unsent = append(unsent, msg.ID)
+			continue
+		}
+		if err := publish(msg); err != nil {
+			r.log(ctx).Warn("Repo: Failed to publish outbox event", "id", msg.ID, "order_uid", msg.OrderUID, "err", err)
+			failed[msg.OrderUID] = true
+			unsent = append(unsent, msg.ID)
+			continue
+		}
+		sent = append(sent, msg.ID)
+	}
+
+	// Unsent messages are released rather than left to the lease, so they are
+	// retried on the next run.
+	err = r.withTx(ctx, func(tx *sql.Tx) error {
+		if len(sent) > 0 {
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE outbox SET published_at = now(), claimed_until = NULL WHERE id = ANY($1)`, pq.Array(sent),
+			); err != nil {
+				r.log(ctx).Error("Repo: Failed to mark outbox events published", "err", err)
+				return classifyError(err)
+			}
+		}
+		if len(unsent) == 0 {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE outbox SET claimed_until = NULL WHERE id = ANY($1)`, pq.Array(unsent),
+		); err != nil {
+			r.log(ctx).Error("Repo: Failed to release outbox events", "err", err)
+			return classifyError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(sent), nil
+}
+
+// claimOutbox reserves up to limit pending messages, oldest first, for
+// outboxClaimLease.
+func (r *Repository) claimOutbox(ctx context.Context, limit int) ([]ports.OutboxMessage, error) {
+	var messages []ports.OutboxMessage
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var locked bool
+		if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext('outbox'), 0)`).Scan(&locked); err != nil {
+			r.log(ctx).Error("Repo: Failed to lock outbox", "err", err)
+			return classifyError(err)
+		}
+		if !locked {
+			return nil
+		}
+
+		rows, err := tx.QueryContext(ctx,
+			`UPDATE outbox SET claimed_until = now() + make_interval(secs => $2)
+			 WHERE id IN (
+			     SELECT o.id FROM outbox o
+			     WHERE o.published_at IS NULL
+			       AND (o.claimed_until IS NULL OR o.claimed_until < now())
+			       AND NOT EXISTS (
+			           SELECT 1 FROM outbox p
+			           WHERE p.aggregate_id = o.aggregate_id
+			             AND p.published_at IS NULL
+			             AND p.id < o.id
+			             AND p.claimed_until >= now())
+			     ORDER BY o.id
+			     LIMIT $1)
+			 RETURNING id, aggregate_id, event_type, payload, created_at`,
+			limit, outboxClaimLease.Seconds())
+		if err != nil {
+			r.log(ctx).Error("Repo: Failed to claim outbox events", "err", err)
+			return classifyError(err)
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				r.log(ctx).Error("failed to close rows", "err", err)
+			}
+		}()
+
+		for rows.Next() {
+			var msg ports.OutboxMessage
+			if err := rows.Scan(&msg.ID, &msg.OrderUID, &msg.EventType, &msg.Payload, &msg.CreatedAt); err != nil {
+				return classifyError(err)
+			}
+			messages = append(messages, msg)
+		}
+		return classifyError(rows.Err())
+	})
+	if err != nil {
+		return nil, err
+	}
+	// UPDATE ... RETURNING doesn't keep the subquery's order.
+	slices.SortFunc(messages, func(a, b ports.OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
+
+func (r *Repository) PurgeOutbox(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, publishedBefore)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to purge outbox", "err", err)
+		return 0, classifyError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, classifyError(err)
+	}
+	return n, nil
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func relayAll(t *testing.T, repo *Repository) []ports.OutboxMessage {
+	t.Helper()
+	var sent []ports.OutboxMessage
+	_, err := repo.RelayOutbox(context.Background(), 100, func(msg ports.OutboxMessage) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	require.NoError(t, err)
+	return sent
+}
+
+func TestRepository_Outbox_EventPerChange(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictOverwrite))
+	ctx := context.Background()
+	uid := testmock.Test_order.OrderUID
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.SaveOrder(ctx, changedOrder()))
+	require.NoError(t, repo.CancelOrder(ctx, uid))
+	_, err := repo.DeleteOrder(ctx, uid)
+	require.NoError(t, err)
+	require.ErrorIs(t, repo.UpdateOrderStatus(ctx, uid, "shipped"), ports.ErrOrderNotFound)
+
+	sent := relayAll(t, repo)
+	var types []string
+	for _, msg := range sent {
+		assert.Equal(t, uid, msg.OrderUID)
+		event, err := order_entity.ParseEvent([]byte(msg.OrderUID), msg.Payload)
+		require.NoError(t, err)
+		assert.Equal(t, msg.EventType, string(event.Type))
+		types = append(types, msg.EventType)
+	}
+	assert.Equal(t, []string{
+		string(order_entity.EventOrderCreated),
+		string(order_entity.EventOrderUpdated),
+		string(order_entity.EventOrderCancelled),
+		string(order_entity.EventOrderDeleted),
+	}, types)
+
+	var created order_entity.Event
+	require.NoError(t, json.Unmarshal(sent[0].Payload, &created))
+	assert.Equal(t, order_entity.StatusCreated, created.Order.Status)
+
+	assert.Empty(t, relayAll(t, repo), "published events must not be relayed again")
+}
+
+func TestRepository_Outbox_RolledBackChangeHasNoEvent(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictReject))
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.ErrorIs(t, repo.SaveOrder(ctx, changedOrder()), ports.ErrOrderConflict)
+
+	assert.Equal(t, 1, countRows(t, db, "outbox"))
+}
+
+func TestRepository_RelayOutbox_KeepsOrderAfterFailure(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	other := testmock.Test_order
+	other.OrderUID = "other-order"
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.SaveOrder(ctx, other))
+	require.NoError(t, repo.CancelOrder(ctx, testmock.Test_order.OrderUID))
+
+	var attempted []int64
+	n, err := repo.RelayOutbox(ctx, 100, func(msg ports.OutboxMessage) error {
+		attempted = append(attempted, msg.ID)
+		if msg.OrderUID == testmock.Test_order.OrderUID {
+			return errors.New("kafka down")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	// The cancellation of the failed order is not attempted ahead of its creation.
+	assert.Equal(t, []int64{1, 2}, attempted)
+
+	sent := relayAll(t, repo)
+	require.Len(t, sent, 2)
+	assert.Equal(t, int64(1), sent[0].ID)
+	assert.Equal(t, int64(3), sent[1].ID)
+}
+
+func TestRepository_RelayOutbox_SkipsOrdersClaimedElsewhere(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	other := testmock.Test_order
+	other.OrderUID = "other-order"
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.SaveOrder(ctx, other))
+	require.NoError(t, repo.CancelOrder(ctx, testmock.Test_order.OrderUID))
+
+	// Another relay is publishing the creation of the first order.
+	_, err := db.Exec(`UPDATE outbox SET claimed_until = now() + interval '1 minute' WHERE id = 1`)
+	require.NoError(t, err)
+
+	sent := relayAll(t, repo)
+	require.Len(t, sent, 1, "the cancellation waits for the claimed creation")
+	assert.Equal(t, "other-order", sent[0].OrderUID)
+
+	// Once the claim runs out, the first order's events are relayed in order.
+	_, err = db.Exec(`UPDATE outbox SET claimed_until = now() - interval '1 second' WHERE id = 1`)
+	require.NoError(t, err)
+	sent = relayAll(t, repo)
+	require.Len(t, sent, 2)
+	assert.Equal(t, int64(1), sent[0].ID)
+	assert.Equal(t, int64(3), sent[1].ID)
+}
+
+func TestRepository_PurgeOutbox(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.CancelOrder(ctx, testmock.Test_order.OrderUID))
+	_, err := repo.RelayOutbox(ctx, 1, func(ports.OutboxMessage) error { return nil })
+	require.NoError(t, err)
+
+	n, err := repo.PurgeOutbox(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 1, countRows(t, db, "outbox"), "pending events are kept")
+}
@@ -3,38 +3,260 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	order_entity "testberry/internal/domain/order"
 	"testberry/internal/ports"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// ConflictPolicy decides what SaveOrder does when an order_uid is already
+// stored with a different payload. Identical payloads are always a no-op.
+type ConflictPolicy string
+
+const (
+	ConflictReject    ConflictPolicy = "reject"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictVersion   ConflictPolicy = "version"
 )
 
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictReject, ConflictOverwrite, ConflictVersion:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown order conflict policy %q", s)
+}
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 type Repository struct {
-	db     *sql.DB
-	logger ports.Logger
+	db             *sql.DB
+	logger         ports.Logger
+	conflictPolicy ConflictPolicy
 }
 
-func NewRepository(db *sql.DB, logger ports.Logger) *Repository {
-	return &Repository{db: db, logger: logger}
+type Option func(*Repository)
+
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(r *Repository) {
+		r.conflictPolicy = policy
+	}
 }
 
-func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) error {
+func NewRepository(db *sql.DB, logger ports.Logger, opts ...Option) *Repository {
+	r := &Repository{db: db, logger: logger, conflictPolicy: ConflictReject}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// log returns the logger with the correlation fields of ctx.
+func (r *Repository) log(ctx context.Context) ports.Logger {
+	return ports.LoggerFromContext(ctx, r.logger)
+}
+
+// Ping checks that Postgres answers, for readiness probes.
+func (r *Repository) Ping(ctx context.Context) error {
+	return classifyError(r.db.PingContext(ctx))
+}
+
+func (r *Repository) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		r.logger.Error("Failed to start transaction", "err", err)
+		r.log(ctx).Error("Failed to start transaction", "err", err)
+		return classifyError(err)
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.log(ctx).Error("Failed to rollback transaction", "err", rollbackErr)
+		}
 		return err
 	}
-	committed := false
-	defer func() {
-		if err != nil && !committed {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				r.logger.Error("Failed to rollback transaction", "err", rollbackErr)
+	if err := tx.Commit(); err != nil {
+		r.log(ctx).Error("Repo: Failed to commit transaction", "err", err)
+		return fmt.Errorf("failed to commit transaction: %w", classifyError(err))
+	}
+	return nil
+}
+
+// lockOrder serializes writers of one order_uid for the rest of the transaction,
+// including the first insert, where there is no row yet for FOR UPDATE to lock.
+func (r *Repository) lockOrder(ctx context.Context, tx *sql.Tx, orderUID string) (version int, found bool, err error) {
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, orderUID); err != nil {
+		r.log(ctx).Error("Repo: Failed to lock order", "err", err)
+		return 0, false, classifyError(err)
+	}
+	err = tx.QueryRowContext(ctx, `SELECT version FROM orders WHERE order_uid = $1 FOR UPDATE`, orderUID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to look up order", "err", err)
+		return 0, false, classifyError(err)
+	}
+	return version, true, nil
+}
+
+func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) error {
+	duplicate := false
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		version, found, err := r.lockOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if err := r.insertOrder(ctx, tx, order); err != nil {
+				return err
 			}
+			order.Status = order_entity.StatusCreated
+			if err := r.recordHistory(ctx, tx, order.OrderUID, order_entity.EventOrderCreated, &order); err != nil {
+				return err
+			}
+			return r.enqueueEvent(ctx, tx, order_entity.Event{
+				Type:     order_entity.EventOrderCreated,
+				OrderUID: order.OrderUID,
+				Order:    &order,
+			})
 		}
-	}()
 
+		stored, err := r.getOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			r.log(ctx).Error("Repo: Failed to load stored order", "err", err)
+			return err
+		}
+		if order_entity.Fingerprint(stored) == order_entity.Fingerprint(order) {
+			duplicate = true
+			return nil
+		}
+		return r.resolveConflict(ctx, tx, stored, order, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	if duplicate {
+		r.log(ctx).Info("Repo: Order already stored, skipping duplicate", "order_uid", order.OrderUID)
+		return nil
+	}
+	r.log(ctx).Info("Repo: Order saved successfully", "order_uid", order.OrderUID)
+	return nil
+}
+
+func (r *Repository) UpdateOrder(ctx context.Context, order order_entity.Order) (string, error) {
+	var previousCustomerID string
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		version, found, err := r.lockOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%w: %s", ports.ErrOrderNotFound, order.OrderUID)
+		}
+
+		stored, err := r.getOrder(ctx, tx, order.OrderUID)
+		if err != nil {
+			r.log(ctx).Error("Repo: Failed to load stored order", "err", err)
+			return err
+		}
+		previousCustomerID = stored.CustomerID
+		if order_entity.Fingerprint(stored) == order_entity.Fingerprint(order) {
+			return nil
+		}
+		return r.replaceOrder(ctx, tx, stored, order, version)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	r.log(ctx).Info("Repo: Order updated successfully", "order_uid", order.OrderUID)
+	return previousCustomerID, nil
+}
+
+// DeleteOrder removes the order with its delivery, payment and items.
+// Deleting an order that does not exist is not an error, so tombstones can be replayed.
+func (r *Repository) DeleteOrder(ctx context.Context, orderUID string) (string, error) {
+	var customerID string
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		_, found, err := r.lockOrder(ctx, tx, orderUID)
+		if err != nil || !found {
+			return err
+		}
+
+		// Items, delivery and payment go with the order: see 0010_schema_hardening.
+		if err := tx.QueryRowContext(ctx,
+			`DELETE FROM orders WHERE order_uid = $1 RETURNING customer_id`, orderUID,
+		).Scan(&customerID); err != nil {
+			r.log(ctx).Error("Repo: Failed to delete order", "err", err)
+			return classifyError(err)
+		}
+		if err := r.recordHistory(ctx, tx, orderUID, order_entity.EventOrderDeleted, nil); err != nil {
+			return err
+		}
+		if err := r.enqueueEvent(ctx, tx, order_entity.Event{Type: order_entity.EventOrderDeleted, OrderUID: orderUID}); err != nil {
+			return err
+		}
+
+		r.log(ctx).Info("Repo: Order deleted", "order_uid", orderUID)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return customerID, nil
+}
+
+func (r *Repository) resolveConflict(ctx context.Context, tx *sql.Tx, stored, order order_entity.Order, version int) error {
+	if r.conflictPolicy == ConflictOverwrite || r.conflictPolicy == ConflictVersion {
+		r.log(ctx).Warn("Repo: Order changed, replacing", "order_uid", order.OrderUID, "policy", r.conflictPolicy)
+		return r.replaceOrder(ctx, tx, stored, order, version)
+	}
+	r.log(ctx).Warn("Repo: Order changed, rejecting", "order_uid", order.OrderUID)
+	return fmt.Errorf("%w: %s", ports.ErrOrderConflict, order.OrderUID)
+}
+
+// replaceOrder overwrites the stored order and bumps its version. Under the
+// version policy the previous payload is archived in order_versions first.
+func (r *Repository) replaceOrder(ctx context.Context, tx *sql.Tx, stored, order order_entity.Order, version int) error {
+	if r.conflictPolicy == ConflictVersion {
+		payload, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO order_versions (order_uid, version, payload) VALUES ($1, $2, $3)`,
+			order.OrderUID, version, string(payload),
+		); err != nil {
+			r.log(ctx).Error("Repo: Failed to archive order version", "err", err)
+			return classifyError(err)
+		}
+	}
+	if err := r.overwriteOrder(ctx, tx, order, version+1); err != nil {
+		return err
+	}
+	// The status is not part of the payload and survives a replacement.
+	order.Status = stored.Status
+	if err := r.recordHistory(ctx, tx, order.OrderUID, order_entity.EventOrderUpdated, &order); err != nil {
+		return err
+	}
+	return r.enqueueEvent(ctx, tx, order_entity.Event{
+		Type:     order_entity.EventOrderUpdated,
+		OrderUID: order.OrderUID,
+		Order:    &order,
+	})
+}
+
+func (r *Repository) insertOrder(ctx context.Context, tx *sql.Tx, order order_entity.Order) error {
 	var deliveryID int
-	err = tx.QueryRowContext(ctx,
+	err := tx.QueryRowContext(ctx,
 		`INSERT INTO delivery (name, phone, zip, city, address, region, email)
 		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
 		order.Delivery.Name,
@@ -46,8 +268,8 @@ func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) er
 		order.Delivery.Email,
 	).Scan(&deliveryID)
 	if err != nil {
-		r.logger.Error("Repo: Failed to insert delivery", "err", err)
-		return err
+		r.log(ctx).Error("Repo: Failed to insert delivery", "err", err)
+		return classifyError(err)
 	}
 
 	var paymentID int
@@ -66,13 +288,16 @@ func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) er
 		order.Payment.CustomFee,
 	).Scan(&paymentID)
 	if err != nil {
-		r.logger.Error("Repo: Failed to insert payment", "err", err)
-		return err
+		r.log(ctx).Error("Repo: Failed to insert payment", "err", err)
+		return classifyError(err)
 	}
 
+	// A new order always starts as created, whatever the payload says: only
+	// status events move it through the state machine.
+	status := order_entity.StatusCreated
 	_, err = tx.ExecContext(ctx,
-		`INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
+		`INSERT INTO orders (order_uid, track_number, entry, delivery_id, payment_id, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, status)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`,
 		order.OrderUID,
 		order.TrackNumber,
 		order.Entry,
@@ -86,14 +311,90 @@ func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) er
 		order.SmID,
 		order.DateCreated,
 		order.OofShard,
+		status,
 	)
 	if err != nil {
-		r.logger.Error("Repo: Failed to insert order", "err", err)
+		r.log(ctx).Error("Repo: Failed to insert order", "err", err)
+		return classifyError(err)
+	}
+	if err := r.recordStatus(ctx, tx, order.OrderUID, "", status); err != nil {
 		return err
 	}
 
+	return r.insertItems(ctx, tx, order)
+}
+
+func (r *Repository) overwriteOrder(ctx context.Context, tx *sql.Tx, order order_entity.Order, version int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE delivery SET name = $2, phone = $3, zip = $4, city = $5, address = $6, region = $7, email = $8
+		 WHERE id = (SELECT delivery_id FROM orders WHERE order_uid = $1)`,
+		order.OrderUID,
+		order.Delivery.Name,
+		order.Delivery.Phone,
+		order.Delivery.Zip,
+		order.Delivery.City,
+		order.Delivery.Address,
+		order.Delivery.Region,
+		order.Delivery.Email,
+	)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to update delivery", "err", err)
+		return classifyError(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE payment SET transaction = $2, request_id = $3, currency = $4, provider = $5, amount = $6,
+		        payment_dt = $7, bank = $8, delivery_cost = $9, goods_total = $10, custom_fee = $11
+		 WHERE id = (SELECT payment_id FROM orders WHERE order_uid = $1)`,
+		order.OrderUID,
+		order.Payment.Transaction,
+		order.Payment.RequestID,
+		order.Payment.Currency,
+		order.Payment.Provider,
+		order.Payment.Amount,
+		order.Payment.PaymentDt,
+		order.Payment.Bank,
+		order.Payment.DeliveryCost,
+		order.Payment.GoodsTotal,
+		order.Payment.CustomFee,
+	)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to update payment", "err", err)
+		return classifyError(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET track_number = $2, entry = $3, locale = $4, internal_signature = $5, customer_id = $6,
+		        delivery_service = $7, shardkey = $8, sm_id = $9, date_created = $10, oof_shard = $11, version = $12
+		 WHERE order_uid = $1`,
+		order.OrderUID,
+		order.TrackNumber,
+		order.Entry,
+		order.Locale,
+		order.InternalSignature,
+		order.CustomerID,
+		order.DeliveryService,
+		order.Shardkey,
+		order.SmID,
+		order.DateCreated,
+		order.OofShard,
+		version,
+	)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to update order", "err", err)
+		return classifyError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM item WHERE order_uid = $1`, order.OrderUID); err != nil {
+		r.log(ctx).Error("Repo: Failed to delete items", "err", err)
+		return classifyError(err)
+	}
+	return r.insertItems(ctx, tx, order)
+}
+
+func (r *Repository) insertItems(ctx context.Context, tx *sql.Tx, order order_entity.Order) error {
 	for _, item := range order.Items {
-		_, err = tx.ExecContext(ctx,
+		_, err := tx.ExecContext(ctx,
 			`INSERT INTO item (chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_uid)
 			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`,
 			item.ChrtID,
@@ -110,26 +411,23 @@ func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) er
 			order.OrderUID,
 		)
 		if err != nil {
-			r.logger.Error("Repo: Failed to insert item", "err", err)
-			return err
+			r.log(ctx).Error("Repo: Failed to insert item", "err", err)
+			return classifyError(err)
 		}
 	}
-	if err = tx.Commit(); err != nil {
-		r.logger.Error("Repo: Failed to commit transaction", "err", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	committed = true
-
-	r.logger.Info("Repo: Order saved successfully", "order_uid", order.OrderUID)
 	return nil
 }
 
 func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_entity.Order, error) {
+	return r.getOrder(ctx, r.db, orderUID)
+}
+
+func (r *Repository) getOrder(ctx context.Context, q querier, orderUID string) (order_entity.Order, error) {
 	var order order_entity.Order
 	query := `SELECT 
 		o.order_uid, o.track_number, o.entry,
 		o.locale, o.internal_signature, o.customer_id,
-		o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+		o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
 		d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
 		p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt,
 		p.bank, p.delivery_cost, p.goods_total, p.custom_fee
@@ -138,7 +436,7 @@ func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_e
 	JOIN payment p ON o.payment_id = p.id
 	WHERE o.order_uid = $1
 	`
-	err := r.db.QueryRowContext(ctx, query, orderUID).Scan(
+	err := q.QueryRowContext(ctx, query, orderUID).Scan(
 		&order.OrderUID,
 		&order.TrackNumber,
 		&order.Entry,
@@ -150,6 +448,7 @@ func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_e
 		&order.SmID,
 		&order.DateCreated,
 		&order.OofShard,
+		&order.Status,
 		&order.Delivery.Name,
 		&order.Delivery.Phone,
 		&order.Delivery.Zip,
@@ -168,23 +467,27 @@ func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_e
 		&order.Payment.GoodsTotal,
 		&order.Payment.CustomFee,
 	)
+	if err == sql.ErrNoRows {
+		return order, fmt.Errorf("%w: %s", ports.ErrOrderNotFound, orderUID)
+	}
 	if err != nil {
-		return order, err
+		return order, classifyError(err)
 	}
 
 	itemsQuery := `
 	SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
 	FROM item
 	WHERE order_uid = $1
+	ORDER BY id
 	`
-	rows, err := r.db.QueryContext(ctx, itemsQuery, orderUID)
+	rows, err := q.QueryContext(ctx, itemsQuery, orderUID)
 	if err != nil {
-		return order, err
+		return order, classifyError(err)
 	}
 
 	defer func() {
 		if err := rows.Close(); err != nil {
-			r.logger.Error("failed to close rows: %v", err)
+			r.log(ctx).Error("failed to close rows", "err", err)
 		}
 	}()
 	for rows.Next() {
@@ -202,92 +505,156 @@ func (r *Repository) GetOrderByID(ctx context.Context, orderUID string) (order_e
 			&item.Brand,
 			&item.Status,
 		); err != nil {
-			return order, err
+			return order, classifyError(err)
 		}
 		order.Items = append(order.Items, item)
 	}
-	return order, nil
+	return order, classifyError(rows.Err())
 }
 
-func (r *Repository) RestoreCache(ctx context.Context) ([]order_entity.Order, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
-		       o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
-		       d.id, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
-		       p.id, p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,
-		       p.delivery_cost, p.goods_total, p.custom_fee
-		FROM orders o
-		JOIN delivery d ON o.delivery_id = d.id
-		JOIN payment p ON o.payment_id = p.id
-	`)
-	if err != nil {
-		return nil, err
+// StreamOrders walks the orders newest first using keyset pagination on
+// (date_created, order_uid) and loads the items of each batch with one query.
+// A batch is fully read and its rows closed before fn sees it, so fn may take
+// its time without holding a connection.
+func (r *Repository) StreamOrders(ctx context.Context, opts ports.StreamOptions, fn func(batch []order_entity.Order) error) error {
+	var (
+		after  *orderCursor
+		total  int
+		batchN int
+	)
+	for opts.Limit <= 0 || total < opts.Limit {
+		size := opts.BatchSize
+		if opts.Limit > 0 {
+			size = min(size, opts.Limit-total)
+		}
+		batch, err := r.loadOrderBatch(ctx, after, opts.Since, size)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := r.loadItems(ctx, batch); err != nil {
+			return err
+		}
+
+		batchN++
+		total += len(batch)
+		r.log(ctx).Debug("Repo: Streamed orders batch", "batch", batchN, "orders", total)
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < size {
+			break
+		}
+		last := batch[len(batch)-1]
+		after = &orderCursor{dateCreated: last.DateCreated, orderUID: last.OrderUID}
+	}
+	return nil
+}
+
+type orderCursor struct {
+	dateCreated time.Time
+	orderUID    string
+}
+
+const orderColumns = `
+	o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
+	o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
+	d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+	p.transaction, p.request_id, p.currency, p.provider, p.amount, p.payment_dt, p.bank,
+	p.delivery_cost, p.goods_total, p.custom_fee
+	FROM orders o
+	JOIN delivery d ON o.delivery_id = d.id
+	JOIN payment p ON o.payment_id = p.id`
+
+func (r *Repository) loadOrderBatch(ctx context.Context, after *orderCursor, since time.Time, limit int) ([]order_entity.Order, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if after != nil {
+		args = append(args, after.dateCreated, after.orderUID)
+		conds = append(conds, fmt.Sprintf("(o.date_created, o.order_uid) < ($%d, $%d)", len(args)-1, len(args)))
 	}
+	if !since.IsZero() {
+		args = append(args, since)
+		conds = append(conds, fmt.Sprintf("o.date_created >= $%d", len(args)))
+	}
+	return r.queryOrders(ctx, conds, args, "o.date_created DESC, o.order_uid DESC", limit)
+}
+
+// queryOrders selects orders without their items matching all conds, whose
+// placeholders are numbered after args.
+func (r *Repository) queryOrders(ctx context.Context, conds []string, args []interface{}, orderBy string, limit int) ([]order_entity.Order, error) {
+	query := `SELECT ` + orderColumns
+	if len(conds) > 0 {
+		query += "\n\tWHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf("\n\tORDER BY %s\n\tLIMIT $%d", orderBy, len(args))
 
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to query orders", "err", err)
+		return nil, classifyError(err)
+	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			r.logger.Error("failed to close rows: %v", err)
+			r.log(ctx).Error("failed to close rows", "err", err)
 		}
 	}()
 
-	var orders []order_entity.Order
-
+	orders := make([]order_entity.Order, 0, limit)
 	for rows.Next() {
 		var o order_entity.Order
-		var d order_entity.Delivery
-		var p order_entity.Payment
-		var deliveryID, paymentID int
-		var dateCreated time.Time
-
-		err := rows.Scan(
+		if err := rows.Scan(
 			&o.OrderUID, &o.TrackNumber, &o.Entry, &o.Locale, &o.InternalSignature,
-			&o.CustomerID, &o.DeliveryService, &o.Shardkey, &o.SmID, &dateCreated, &o.OofShard,
-			&deliveryID, &d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email,
-			&paymentID, &p.Transaction, &p.RequestID, &p.Currency, &p.Provider, &p.Amount, &p.PaymentDt, &p.Bank,
-			&p.DeliveryCost, &p.GoodsTotal, &p.CustomFee,
-		)
-		if err != nil {
-			return nil, err
+			&o.CustomerID, &o.DeliveryService, &o.Shardkey, &o.SmID, &o.DateCreated, &o.OofShard, &o.Status,
+			&o.Delivery.Name, &o.Delivery.Phone, &o.Delivery.Zip, &o.Delivery.City, &o.Delivery.Address, &o.Delivery.Region, &o.Delivery.Email,
+			&o.Payment.Transaction, &o.Payment.RequestID, &o.Payment.Currency, &o.Payment.Provider, &o.Payment.Amount,
+			&o.Payment.PaymentDt, &o.Payment.Bank, &o.Payment.DeliveryCost, &o.Payment.GoodsTotal, &o.Payment.CustomFee,
+		); err != nil {
+			return nil, classifyError(err)
 		}
+		orders = append(orders, o)
+	}
+	return orders, classifyError(rows.Err())
+}
 
-		o.Delivery = d
-		o.Payment = p
-		o.DateCreated = dateCreated
+// loadItems fills in the items of every order in batch with a single query.
+func (r *Repository) loadItems(ctx context.Context, batch []order_entity.Order) error {
+	uids := make([]string, len(batch))
+	index := make(map[string]int, len(batch))
+	for i, o := range batch {
+		uids[i] = o.OrderUID
+		index[o.OrderUID] = i
+	}
 
-		itemRows, err := r.db.QueryContext(ctx, `
-			SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-			FROM item WHERE order_uid = $1
-		`, o.OrderUID)
-		if err != nil {
-			return nil, err
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_uid, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+		FROM item
+		WHERE order_uid = ANY($1)
+		ORDER BY order_uid, id`, pq.Array(uids))
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to read items batch", "err", err)
+		return classifyError(err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			r.log(ctx).Error("failed to close rows", "err", err)
 		}
+	}()
 
-		var items []order_entity.Item
-		for itemRows.Next() {
-			var it order_entity.Item
-			err := itemRows.Scan(&it.ChrtID, &it.TrackNumber, &it.Price, &it.Rid, &it.Name, &it.Sale,
-				&it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status)
-			if err != nil {
-				defer func() {
-					if err := itemRows.Close(); err != nil {
-						r.logger.Error("failed to close rows: %v", err)
-					}
-				}()
-				return nil, err
-			}
-			items = append(items, it)
+	for rows.Next() {
+		var uid string
+		var it order_entity.Item
+		if err := rows.Scan(&uid, &it.ChrtID, &it.TrackNumber, &it.Price, &it.Rid, &it.Name, &it.Sale,
+			&it.Size, &it.TotalPrice, &it.NmID, &it.Brand, &it.Status); err != nil {
+			return classifyError(err)
 		}
-
-		defer func() {
-			if err := itemRows.Close(); err != nil {
-				r.logger.Error("failed to close rows: %v", err)
-			}
-		}()
-
-		o.Items = items
-
-		orders = append(orders, o)
+		i := index[uid]
+		batch[i].Items = append(batch[i].Items, it)
 	}
-
-	return orders, nil
+	return classifyError(rows.Err())
 }
@@ -0,0 +1,346 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"testberry/deployments/deployments/migrations"
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+var (
+	pgOnce      sync.Once
+	pgContainer *tcpostgres.PostgresContainer
+	pgDB        *sql.DB
+	pgErr       error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if pgDB != nil {
+		if err := pgDB.Close(); err != nil {
+			log.Printf("failed to close test db: %v", err)
+		}
+	}
+	if pgContainer != nil {
+		if err := testcontainers.TerminateContainer(pgContainer); err != nil {
+			log.Printf("failed to terminate postgres container: %v", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// testDB starts one Postgres container per package run with every up migration
+// applied, and empties the tables before each test.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	pgOnce.Do(func() {
+		ctx := context.Background()
+		pgContainer, pgErr = tcpostgres.Run(ctx, "postgres:16",
+			tcpostgres.WithDatabase("orders_db"),
+			tcpostgres.WithUsername("order_user"),
+			tcpostgres.WithPassword("order_password"),
+			tcpostgres.BasicWaitStrategies(),
+		)
+		if pgErr != nil {
+			return
+		}
+		var connStr string
+		connStr, pgErr = pgContainer.ConnectionString(ctx, "sslmode=disable")
+		if pgErr != nil {
+			return
+		}
+		if pgDB, pgErr = ConnectDB(connStr); pgErr != nil {
+			return
+		}
+		var migrator *Migrator
+		if migrator, pgErr = NewMigrator(pgDB, migrations.FS, &testmock.TestLogger{}); pgErr != nil {
+			return
+		}
+		_, pgErr = migrator.Up(ctx)
+	})
+	require.NoError(t, pgErr)
+
+	_, err := pgDB.Exec(`TRUNCATE item, orders, delivery, payment, order_versions, outbox, order_status_history, order_history RESTART IDENTITY CASCADE`)
+	require.NoError(t, err)
+	return pgDB
+}
+
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var n int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM `+table).Scan(&n))
+	return n
+}
+
+func assertRowCounts(t *testing.T, db *sql.DB, orders, deliveries, payments, items int) {
+	t.Helper()
+	assert.Equal(t, orders, countRows(t, db, "orders"), "orders")
+	assert.Equal(t, deliveries, countRows(t, db, "delivery"), "delivery")
+	assert.Equal(t, payments, countRows(t, db, "payment"), "payment")
+	assert.Equal(t, items, countRows(t, db, "item"), "item")
+}
+
+func changedOrder() order_entity.Order {
+	order := testmock.Test_order
+	order.Delivery.City = "Haifa"
+	order.Payment.Amount = 2000
+	order.Items = append([]order_entity.Item{}, testmock.Test_order.Items...)
+	order.Items[0].Price = 500
+	return order
+}
+
+func TestRepository_SaveOrder_RoundTrip(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+
+	stored, err := repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.Fingerprint(testmock.Test_order), order_entity.Fingerprint(stored))
+}
+
+func TestRepository_SaveOrder_IdenticalRedeliveryIsNoop(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+
+	assertRowCounts(t, db, 1, 1, 1, len(testmock.Test_order.Items))
+}
+
+func TestRepository_SaveOrder_ConcurrentRedelivery(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = repo.SaveOrder(ctx, testmock.Test_order)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assertRowCounts(t, db, 1, 1, 1, len(testmock.Test_order.Items))
+}
+
+func TestRepository_SaveOrder_ChangedPayloadRejected(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictReject))
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	err := repo.SaveOrder(ctx, changedOrder())
+	require.ErrorIs(t, err, ports.ErrOrderConflict)
+
+	assertRowCounts(t, db, 1, 1, 1, len(testmock.Test_order.Items))
+	stored, err := repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, testmock.Test_order.Delivery.City, stored.Delivery.City)
+}
+
+func TestRepository_SaveOrder_ChangedPayloadOverwritten(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictOverwrite))
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	changed := changedOrder()
+	require.NoError(t, repo.SaveOrder(ctx, changed))
+
+	assertRowCounts(t, db, 1, 1, 1, len(changed.Items))
+	stored, err := repo.GetOrderByID(ctx, changed.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.Fingerprint(changed), order_entity.Fingerprint(stored))
+	assert.Equal(t, 0, countRows(t, db, "order_versions"))
+}
+
+func TestRepository_SaveOrder_ChangedPayloadVersioned(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{}, WithConflictPolicy(ConflictVersion))
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	changed := changedOrder()
+	require.NoError(t, repo.SaveOrder(ctx, changed))
+	// Redelivering the latest version must not create another one.
+	require.NoError(t, repo.SaveOrder(ctx, changed))
+
+	assertRowCounts(t, db, 1, 1, 1, len(changed.Items))
+
+	var version int
+	require.NoError(t, db.QueryRow(`SELECT version FROM orders WHERE order_uid = $1`, changed.OrderUID).Scan(&version))
+	assert.Equal(t, 2, version)
+
+	var archivedCity string
+	require.NoError(t, db.QueryRow(
+		`SELECT payload->'delivery'->>'city' FROM order_versions WHERE order_uid = $1 AND version = 1`,
+		changed.OrderUID,
+	).Scan(&archivedCity))
+	assert.Equal(t, testmock.Test_order.Delivery.City, archivedCity)
+}
+
+func TestRepository_UpdateOrder(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	_, err := repo.UpdateOrder(ctx, testmock.Test_order)
+	require.ErrorIs(t, err, ports.ErrOrderNotFound)
+	assertRowCounts(t, db, 0, 0, 0, 0)
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	changed := changedOrder()
+	changed.CustomerID = "new-customer"
+	previous, err := repo.UpdateOrder(ctx, changed)
+	require.NoError(t, err)
+	assert.Equal(t, testmock.Test_order.CustomerID, previous)
+
+	stored, err := repo.GetOrderByID(ctx, changed.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.Fingerprint(changed), order_entity.Fingerprint(stored))
+	assertRowCounts(t, db, 1, 1, 1, len(changed.Items))
+}
+
+func TestRepository_UpdateOrderStatus(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	require.ErrorIs(t, repo.CancelOrder(ctx, testmock.Test_order.OrderUID), ports.ErrOrderNotFound)
+
+	// The payload's status is ignored: a new order always starts as created.
+	order := testmock.Test_order
+	order.Status = order_entity.StatusDelivered
+	require.NoError(t, repo.SaveOrder(ctx, order))
+	stored, err := repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.StatusCreated, stored.Status)
+	timeline, err := repo.OrderTimeline(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	require.Len(t, timeline, 1)
+	assert.Equal(t, order_entity.StatusCreated, timeline[0].To)
+
+	require.NoError(t, repo.CancelOrder(ctx, testmock.Test_order.OrderUID))
+	stored, err = repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.StatusCancelled, stored.Status)
+
+	// A redelivered creation message must not reset the status.
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	stored, err = repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.StatusCancelled, stored.Status)
+}
+
+func TestRepository_DeleteOrder(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	customerID, err := repo.DeleteOrder(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, testmock.Test_order.CustomerID, customerID)
+	assertRowCounts(t, db, 0, 0, 0, 0)
+
+	// Replayed tombstones are a no-op.
+	customerID, err = repo.DeleteOrder(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Empty(t, customerID)
+
+	_, err = repo.GetOrderByID(ctx, testmock.Test_order.OrderUID)
+	require.ErrorIs(t, err, ports.ErrOrderNotFound)
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, s := range []string{"reject", "overwrite", "version"} {
+		p, err := ParseConflictPolicy(s)
+		assert.NoError(t, err)
+		assert.Equal(t, ConflictPolicy(s), p)
+	}
+	_, err := ParseConflictPolicy("merge")
+	assert.Error(t, err)
+}
+
+func TestRepository_StreamOrders(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		order := testmock.Test_order
+		order.OrderUID = fmt.Sprintf("stream-order-%d", i)
+		// Two orders share a timestamp so the order_uid tie-breaker is exercised.
+		order.DateCreated = base.Add(time.Duration(i/2) * time.Hour)
+		require.NoError(t, repo.SaveOrder(ctx, order))
+	}
+	want := []string{"stream-order-4", "stream-order-3", "stream-order-2", "stream-order-1", "stream-order-0"}
+
+	var got []string
+	var batches int
+	err := repo.StreamOrders(ctx, ports.StreamOptions{BatchSize: 2}, func(batch []order_entity.Order) error {
+		batches++
+		for _, o := range batch {
+			assert.Len(t, o.Items, len(testmock.Test_order.Items))
+			got = append(got, o.OrderUID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 3, batches)
+
+	stop := errors.New("stop")
+	err = repo.StreamOrders(ctx, ports.StreamOptions{BatchSize: 2}, func([]order_entity.Order) error { return stop })
+	assert.ErrorIs(t, err, stop)
+
+	got = nil
+	err = repo.StreamOrders(ctx, ports.StreamOptions{BatchSize: 2, Limit: 3}, func(batch []order_entity.Order) error {
+		for _, o := range batch {
+			got = append(got, o.OrderUID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want[:3], got)
+
+	got = nil
+	err = repo.StreamOrders(ctx, ports.StreamOptions{BatchSize: 10, Since: base.Add(time.Hour)}, func(batch []order_entity.Order) error {
+		for _, o := range batch {
+			got = append(got, o.OrderUID)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, want[:3], got)
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+)
+
+// UpdateOrderStatus moves the order to status if order_entity.CheckTransition
+// allows it.
+func (r *Repository) UpdateOrderStatus(ctx context.Context, orderUID string, status string) error {
+	return r.setStatus(ctx, orderUID, status, order_entity.EventOrderStatusChanged)
+}
+
+func (r *Repository) CancelOrder(ctx context.Context, orderUID string) error {
+	return r.setStatus(ctx, orderUID, order_entity.StatusCancelled, order_entity.EventOrderCancelled)
+}
+
+// setStatus applies a transition to the order and its items and records it in
+// order_status_history in one transaction. Moving an order to the status it is already in is a no-op,
+// so a redelivered event doesn't fail or show up twice in the timeline.
+func (r *Repository) setStatus(ctx context.Context, orderUID, status string, eventType order_entity.EventType) error {
+	changed := false
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE order_uid = $1 FOR UPDATE`, orderUID).Scan(&current)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ports.ErrOrderNotFound, orderUID)
+		}
+		if err != nil {
+			r.log(ctx).Error("Repo: Failed to look up order status", "err", err)
+			return classifyError(err)
+		}
+		if current == status {
+			return nil
+		}
+		if err := order_entity.CheckTransition(current, status); err != nil {
+			return fmt.Errorf("order %s: %w", orderUID, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET status = $2 WHERE order_uid = $1`, orderUID, status); err != nil {
+			r.log(ctx).Error("Repo: Failed to update order status", "err", err)
+			return classifyError(err)
+		}
+		// CheckTransition only lets known statuses through, and each has a code.
+		itemStatus, _ := order_entity.ItemStatusFor(status)
+		if _, err := tx.ExecContext(ctx, `UPDATE item SET status = $2 WHERE order_uid = $1`, orderUID, itemStatus); err != nil {
+			r.log(ctx).Error("Repo: Failed to update item statuses", "err", err)
+			return classifyError(err)
+		}
+		if err := r.recordStatus(ctx, tx, orderUID, current, status); err != nil {
+			return err
+		}
+		snapshot, err := r.getOrder(ctx, tx, orderUID)
+		if err != nil {
+			return err
+		}
+		if err := r.recordHistory(ctx, tx, orderUID, eventType, &snapshot); err != nil {
+			return err
+		}
+		changed = true
+		return r.enqueueEvent(ctx, tx, order_entity.Event{Type: eventType, OrderUID: orderUID, Status: status})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		r.log(ctx).Info("Repo: Order already in status, skipping", "order_uid", orderUID, "status", status)
+		return nil
+	}
+	r.log(ctx).Info("Repo: Order status updated", "order_uid", orderUID, "status", status)
+	return nil
+}
+
+// recordStatus appends to the order's timeline. from is empty for the status
+// an order is created with.
+func (r *Repository) recordStatus(ctx context.Context, tx *sql.Tx, orderUID, from, to string) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO order_status_history (order_uid, from_status, to_status) VALUES ($1, NULLIF($2, ''), $3)`,
+		orderUID, from, to)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to record order status", "err", err)
+		return classifyError(err)
+	}
+	return nil
+}
+
+// OrderTimeline returns the order's status changes, oldest first.
+func (r *Repository) OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, changed_at
+		FROM order_status_history
+		WHERE order_uid = $1
+		ORDER BY id`, orderUID)
+	if err != nil {
+		r.log(ctx).Error("Repo: Failed to read order timeline", "err", err)
+		return nil, classifyError(err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			r.log(ctx).Error("failed to close rows", "err", err)
+		}
+	}()
+
+	var timeline []order_entity.StatusChange
+	for rows.Next() {
+		var c order_entity.StatusChange
+		if err := rows.Scan(&c.From, &c.To, &c.ChangedAt); err != nil {
+			return nil, classifyError(err)
+		}
+		timeline = append(timeline, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	// Every stored order has at least the entry it was created with.
+	if len(timeline) == 0 {
+		return nil, fmt.Errorf("%w: %s", ports.ErrOrderNotFound, orderUID)
+	}
+	return timeline, nil
+}
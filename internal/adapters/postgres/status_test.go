@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_StatusTransitions(t *testing.T) {
+	db := testDB(t)
+	repo := NewRepository(db, &testmock.TestLogger{})
+	ctx := context.Background()
+	uid := testmock.Test_order.OrderUID
+
+	_, err := repo.OrderTimeline(ctx, uid)
+	require.ErrorIs(t, err, ports.ErrOrderNotFound)
+
+	require.NoError(t, repo.SaveOrder(ctx, testmock.Test_order))
+	require.NoError(t, repo.UpdateOrderStatus(ctx, uid, order_entity.StatusPaid))
+	// Redelivered: no error and no second timeline entry.
+	require.NoError(t, repo.UpdateOrderStatus(ctx, uid, order_entity.StatusPaid))
+
+	err = repo.UpdateOrderStatus(ctx, uid, order_entity.StatusDelivered)
+	require.ErrorIs(t, err, order_entity.ErrInvalidTransition)
+	require.ErrorIs(t, repo.UpdateOrderStatus(ctx, uid, "lost"), order_entity.ErrUnknownStatus)
+
+	require.NoError(t, repo.UpdateOrderStatus(ctx, uid, order_entity.StatusAssembling))
+	require.NoError(t, repo.UpdateOrderStatus(ctx, uid, order_entity.StatusShipped))
+	require.ErrorIs(t, repo.CancelOrder(ctx, uid), order_entity.ErrInvalidTransition)
+
+	stored, err := repo.GetOrderByID(ctx, uid)
+	require.NoError(t, err)
+	assert.Equal(t, order_entity.StatusShipped, stored.Status)
+	require.NotEmpty(t, stored.Items)
+	for _, item := range stored.Items {
+		assert.Equal(t, order_entity.ItemStatusShipped, item.Status, "items move with the order")
+	}
+
+	timeline, err := repo.OrderTimeline(ctx, uid)
+	require.NoError(t, err)
+	want := []order_entity.StatusChange{
+		{To: order_entity.StatusCreated},
+		{From: order_entity.StatusCreated, To: order_entity.StatusPaid},
+		{From: order_entity.StatusPaid, To: order_entity.StatusAssembling},
+		{From: order_entity.StatusAssembling, To: order_entity.StatusShipped},
+	}
+	require.Len(t, timeline, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].From, timeline[i].From)
+		assert.Equal(t, want[i].To, timeline[i].To)
+		assert.False(t, timeline[i].ChangedAt.IsZero())
+	}
+
+	var events int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM outbox WHERE event_type = $1`, order_entity.EventOrderStatusChanged).Scan(&events))
+	assert.Equal(t, 3, events, "rejected and repeated transitions publish nothing")
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+)
+
+// Cache traces every call in a cache.<Method> span.
+type Cache struct {
+	next ports.Cache
+}
+
+func NewCache(next ports.Cache) *Cache {
+	return &Cache{next: next}
+}
+
+func (c *Cache) Get(ctx context.Context, uid string) (_ order_entity.Order, found bool, err error) {
+	ctx, span := start(ctx, "cache.Get", orderUID(uid))
+	defer func() {
+		span.SetAttributes(attribute.Bool("cache.hit", found))
+		end(span, err)
+	}()
+	return c.next.Get(ctx, uid)
+}
+
+func (c *Cache) Set(ctx context.Context, order order_entity.Order) (err error) {
+	ctx, span := start(ctx, "cache.Set", orderUID(order.OrderUID))
+	defer func() { end(span, err) }()
+	return c.next.Set(ctx, order)
+}
+
+func (c *Cache) Delete(ctx context.Context, uid string) (err error) {
+	ctx, span := start(ctx, "cache.Delete", orderUID(uid))
+	defer func() { end(span, err) }()
+	return c.next.Delete(ctx, uid)
+}
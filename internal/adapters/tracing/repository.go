@@ -0,0 +1,99 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+)
+
+// Repository traces every call, each a Postgres transaction or query, in a
+// postgres.<Method> span.
+type Repository struct {
+	next ports.Repository
+}
+
+func NewRepository(next ports.Repository) *Repository {
+	return &Repository{next: next}
+}
+
+func (r *Repository) SaveOrder(ctx context.Context, order order_entity.Order) (err error) {
+	ctx, span := start(ctx, "postgres.SaveOrder", orderUID(order.OrderUID))
+	defer func() { end(span, err) }()
+	return r.next.SaveOrder(ctx, order)
+}
+
+func (r *Repository) UpdateOrder(ctx context.Context, order order_entity.Order) (_ string, err error) {
+	ctx, span := start(ctx, "postgres.UpdateOrder", orderUID(order.OrderUID))
+	defer func() { end(span, err) }()
+	return r.next.UpdateOrder(ctx, order)
+}
+
+func (r *Repository) UpdateOrderStatus(ctx context.Context, uid string, status string) (err error) {
+	ctx, span := start(ctx, "postgres.UpdateOrderStatus", orderUID(uid), attribute.String("order.status", status))
+	defer func() { end(span, err) }()
+	return r.next.UpdateOrderStatus(ctx, uid, status)
+}
+
+func (r *Repository) CancelOrder(ctx context.Context, uid string) (err error) {
+	ctx, span := start(ctx, "postgres.CancelOrder", orderUID(uid))
+	defer func() { end(span, err) }()
+	return r.next.CancelOrder(ctx, uid)
+}
+
+func (r *Repository) OrderTimeline(ctx context.Context, uid string) (_ []order_entity.StatusChange, err error) {
+	ctx, span := start(ctx, "postgres.OrderTimeline", orderUID(uid))
+	defer func() { end(span, err) }()
+	return r.next.OrderTimeline(ctx, uid)
+}
+
+func (r *Repository) DeleteOrder(ctx context.Context, uid string) (_ string, err error) {
+	ctx, span := start(ctx, "postgres.DeleteOrder", orderUID(uid))
+	defer func() { end(span, err) }()
+	return r.next.DeleteOrder(ctx, uid)
+}
+
+func (r *Repository) GetOrderByID(ctx context.Context, uid string) (_ order_entity.Order, err error) {
+	ctx, span := start(ctx, "postgres.GetOrderByID", orderUID(uid))
+	defer func() { end(span, err) }()
+	return r.next.GetOrderByID(ctx, uid)
+}
+
+func (r *Repository) GetOrderAsOf(ctx context.Context, uid string, at time.Time) (_ order_entity.Order, err error) {
+	ctx, span := start(ctx, "postgres.GetOrderAsOf", orderUID(uid), attribute.String("order.as_of", at.Format(time.RFC3339)))
+	defer func() { end(span, err) }()
+	return r.next.GetOrderAsOf(ctx, uid, at)
+}
+
+func (r *Repository) ListOrders(ctx context.Context, query ports.OrderQuery) (_ ports.OrderPage, err error) {
+	ctx, span := start(ctx, "postgres.ListOrders")
+	defer func() { end(span, err) }()
+	return r.next.ListOrders(ctx, query)
+}
+
+func (r *Repository) ListCustomerOrders(ctx context.Context, query ports.CustomerOrdersQuery) (_ ports.CustomerOrdersPage, err error) {
+	ctx, span := start(ctx, "postgres.ListCustomerOrders")
+	defer func() { end(span, err) }()
+	return r.next.ListCustomerOrders(ctx, query)
+}
+
+func (r *Repository) FindByTrackNumber(ctx context.Context, trackNumber string, limit int) (_ []ports.OrderMatch, err error) {
+	ctx, span := start(ctx, "postgres.FindByTrackNumber")
+	defer func() { end(span, err) }()
+	return r.next.FindByTrackNumber(ctx, trackNumber, limit)
+}
+
+func (r *Repository) FindByPaymentID(ctx context.Context, paymentID string, limit int) (_ []ports.OrderMatch, err error) {
+	ctx, span := start(ctx, "postgres.FindByPaymentID")
+	defer func() { end(span, err) }()
+	return r.next.FindByPaymentID(ctx, paymentID, limit)
+}
+
+func (r *Repository) StreamOrders(ctx context.Context, opts ports.StreamOptions, fn func(batch []order_entity.Order) error) (err error) {
+	ctx, span := start(ctx, "postgres.StreamOrders")
+	defer func() { end(span, err) }()
+	return r.next.StreamOrders(ctx, opts, fn)
+}
@@ -0,0 +1,98 @@
+// Package tracing sets up OpenTelemetry and traces the service with
+// decorators around the ports and a middleware for HTTP routes.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"testberry/internal/ports"
+)
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+var tracer = otel.Tracer("testberry/internal/adapters/tracing")
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. exporter is none, stdout (written to stderr) or otlp; an empty endpoint leaves the
+// OTLP exporter to its OTEL_EXPORTER_OTLP_* defaults. The returned function
+// flushes pending spans.
+func Setup(ctx context.Context, exporter, serviceName, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case ExporterNone:
+		// Trace context still passes through, but nothing is recorded.
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		// Spans go to stderr: stdout carries the JSON log records, and mixing
+		// the two would break log parsing.
+		var err error
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr)); err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		var err error
+		if spanExporter, err = otlptracehttp.New(ctx, opts...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// InstrumentRoute traces requests to next in spans named after route, the
+// pattern it is registered with, continuing the caller's trace if it sent one.
+func InstrumentRoute(route string, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, route)
+}
+
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end records err on span, unless it only says the order doesn't exist.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ports.ErrOrderNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func orderUID(uid string) attribute.KeyValue {
+	return attribute.String("order.uid", uid)
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+)
+
+func TestDecorators_CreateChildSpans(t *testing.T) {
+	// Setup with the none exporter installs the propagator only, so the
+	// recording provider is set up here.
+	_, err := Setup(context.Background(), ExporterNone, "order-service", "")
+	require.NoError(t, err)
+	recorded := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorded)))
+
+	repo := new(testmock.MockRepository)
+	repo.On("GetOrderByID", mock.Anything, "missing").Return(order_entity.Order{}, fmt.Errorf("%w: missing", ports.ErrOrderNotFound))
+	repo.On("SaveOrder", mock.Anything, testmock.Test_order).Return(assert.AnError)
+	cache := new(testmock.MockCache)
+	cache.On("Get", mock.Anything, testmock.Test_order.OrderUID).Return(testmock.Test_order, true, nil)
+
+	handler := InstrumentRoute("/order/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		_, _, _ = NewCache(cache).Get(ctx, testmock.Test_order.OrderUID)
+		_, _ = NewRepository(repo).GetOrderByID(ctx, "missing")
+		_ = NewRepository(repo).SaveOrder(ctx, testmock.Test_order)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order/"+testmock.Test_order.OrderUID, nil))
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range recorded.GetSpans().Snapshots() {
+		byName[s.Name()] = s
+	}
+	require.Contains(t, byName, "/order/")
+	root := byName["/order/"].SpanContext()
+	for _, name := range []string{"cache.Get", "postgres.GetOrderByID", "postgres.SaveOrder"} {
+		require.Contains(t, byName, name)
+		assert.Equal(t, root.SpanID(), byName[name].Parent().SpanID(), name)
+	}
+	assert.Equal(t, codes.Unset, byName["postgres.GetOrderByID"].Status().Code, "unknown orders are not errors")
+	assert.Equal(t, codes.Error, byName["postgres.SaveOrder"].Status().Code)
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), "zipkin", "order-service", "")
+	assert.Error(t, err)
+}
@@ -8,4 +8,5 @@ import (
 type Cache interface {
 	Set(ctx context.Context, order order_entity.Order) error
 	Get(ctx context.Context, orderUID string) (order_entity.Order, bool, error)
+	Delete(ctx context.Context, orderUID string) error
 }
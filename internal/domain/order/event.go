@@ -0,0 +1,99 @@
+package order_entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventOrderCreated       EventType = "order.created"
+	EventOrderUpdated       EventType = "order.updated"
+	EventOrderStatusChanged EventType = "order.status_changed"
+	EventOrderCancelled     EventType = "order.cancelled"
+	EventOrderDeleted       EventType = "order.deleted"
+)
+
+// EventVersion is the envelope schema version this service understands.
+const EventVersion = 1
+
+var ErrInvalidEvent = errors.New("invalid order event")
+
+// Event is the envelope carried on the orders topic. Order is set for
+// order.created and order.updated, Status for order.status_changed.
+// OccurredAt is nil for tombstones and bare orders, which don't carry it.
+type Event struct {
+	Type       EventType  `json:"type"`
+	Version    int        `json:"version"`
+	OrderUID   string     `json:"order_uid"`
+	OccurredAt *time.Time `json:"occurred_at,omitempty"`
+	Order      *Order     `json:"order,omitempty"`
+	Status     string     `json:"status,omitempty"`
+}
+
+// ParseEvent decodes a record from the orders topic. A record without a value
+// is a Kafka tombstone and deletes the order named by its key; a JSON object
+// without a "type" field is a bare Order from a legacy producer and is treated
+// as order.created.
+func ParseEvent(key, value []byte) (Event, error) {
+	if len(value) == 0 {
+		if len(key) == 0 {
+			return Event{}, fmt.Errorf("%w: tombstone without a key", ErrInvalidEvent)
+		}
+		return Event{Type: EventOrderDeleted, Version: EventVersion, OrderUID: string(key)}, nil
+	}
+
+	var probe struct {
+		Type *EventType `json:"type"`
+	}
+	if err := json.Unmarshal(value, &probe); err != nil {
+		return Event{}, err
+	}
+
+	if probe.Type == nil {
+		var order Order
+		if err := json.Unmarshal(value, &order); err != nil {
+			return Event{}, err
+		}
+		return Event{Type: EventOrderCreated, Version: EventVersion, OrderUID: order.OrderUID, Order: &order}, nil
+	}
+
+	var event Event
+	if err := json.Unmarshal(value, &event); err != nil {
+		return Event{}, err
+	}
+	if event.OrderUID == "" && event.Order != nil {
+		event.OrderUID = event.Order.OrderUID
+	}
+	return event, event.validate()
+}
+
+func (e Event) validate() error {
+	if e.Version != EventVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidEvent, e.Version)
+	}
+	if e.OrderUID == "" {
+		return fmt.Errorf("%w: order_uid is required", ErrInvalidEvent)
+	}
+
+	switch e.Type {
+	case EventOrderCreated, EventOrderUpdated:
+		if e.Order == nil {
+			return fmt.Errorf("%w: %s requires an order", ErrInvalidEvent, e.Type)
+		}
+		if e.Order.OrderUID != e.OrderUID {
+			return fmt.Errorf("%w: order_uid %q does not match order %q", ErrInvalidEvent, e.OrderUID, e.Order.OrderUID)
+		}
+	case EventOrderStatusChanged:
+		if e.Status == "" {
+			return fmt.Errorf("%w: %s requires a status", ErrInvalidEvent, e.Type)
+		}
+	case EventOrderCancelled, EventOrderDeleted:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidEvent, e.Type)
+	}
+	return nil
+}
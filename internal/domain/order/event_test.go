@@ -0,0 +1,79 @@
+package order_entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseEvent_BareOrderIsCreated(t *testing.T) {
+	event, err := ParseEvent([]byte("key"), []byte(`{"order_uid":"b563feb7b2b84b6test1","track_number":"WBILMTESTTRACK"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Type != EventOrderCreated {
+		t.Errorf("Expected %s, got %s", EventOrderCreated, event.Type)
+	}
+	if event.Order == nil || event.Order.TrackNumber != "WBILMTESTTRACK" {
+		t.Errorf("Expected the bare order to be carried, got %+v", event.Order)
+	}
+	if event.OrderUID != "b563feb7b2b84b6test1" {
+		t.Errorf("Expected order_uid from the order, got %s", event.OrderUID)
+	}
+}
+
+func TestParseEvent_Envelope(t *testing.T) {
+	order := Order{OrderUID: "b563feb7b2b84b6test1"}
+	data, err := json.Marshal(Event{Type: EventOrderUpdated, Version: EventVersion, Order: &order})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := ParseEvent(nil, data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Type != EventOrderUpdated || event.OrderUID != order.OrderUID {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if bytes.Contains(data, []byte("occurred_at")) {
+		t.Errorf("Expected occurred_at to be omitted when unset, got %s", data)
+	}
+}
+
+func TestParseEvent_Tombstone(t *testing.T) {
+	event, err := ParseEvent([]byte("b563feb7b2b84b6test1"), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if event.Type != EventOrderDeleted || event.OrderUID != "b563feb7b2b84b6test1" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	if _, err := ParseEvent(nil, nil); !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected ErrInvalidEvent for a tombstone without key, got %v", err)
+	}
+}
+
+func TestParseEvent_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unsupported version":   `{"type":"order.cancelled","version":2,"order_uid":"b563feb7b2b84b6test1"}`,
+		"unknown type":          `{"type":"order.teleported","version":1,"order_uid":"b563feb7b2b84b6test1"}`,
+		"missing order_uid":     `{"type":"order.cancelled","version":1}`,
+		"created without order": `{"type":"order.created","version":1,"order_uid":"b563feb7b2b84b6test1"}`,
+		"status without status": `{"type":"order.status_changed","version":1,"order_uid":"b563feb7b2b84b6test1"}`,
+		"mismatched order_uid":  `{"type":"order.updated","version":1,"order_uid":"a","order":{"order_uid":"b"}}`,
+	}
+	for name, payload := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseEvent(nil, []byte(payload)); !errors.Is(err, ErrInvalidEvent) {
+				t.Errorf("Expected ErrInvalidEvent, got %v", err)
+			}
+		})
+	}
+
+	if _, err := ParseEvent(nil, []byte(`{invalid_json}`)); err == nil || errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected a JSON syntax error, got %v", err)
+	}
+}
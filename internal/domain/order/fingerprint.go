@@ -0,0 +1,30 @@
+package order_entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Fingerprint returns a content hash of the order that survives a round trip
+// through the database: date_created is stored as a TIMESTAMP, so only its wall
+// clock at microsecond precision is compared, and a nil item list equals an empty one.
+// Status is left out: it is changed by lifecycle events, not by the order payload.
+func Fingerprint(order Order) string {
+	normalized := order
+	normalized.Status = ""
+	t := order.DateCreated
+	normalized.DateCreated = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).
+		Round(time.Microsecond)
+	if normalized.Items == nil {
+		normalized.Items = []Item{}
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
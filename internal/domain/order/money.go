@@ -0,0 +1,131 @@
+package order_entity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// currencyMinorUnits maps the ISO 4217 codes we accept to the number of
+// digits after the decimal point of their minor unit.
+var currencyMinorUnits = map[string]int{
+	"AED": 2, "AMD": 2, "AZN": 2, "BHD": 3, "BYN": 2, "CAD": 2, "CHF": 2,
+	"CNY": 2, "CZK": 2, "EUR": 2, "GBP": 2, "GEL": 2, "ILS": 2, "INR": 2,
+	"JOD": 3, "JPY": 0, "KGS": 2, "KRW": 0, "KWD": 3, "KZT": 2, "MDL": 2,
+	"OMR": 3, "PLN": 2, "RUB": 2, "TJS": 2, "TRY": 2, "UAH": 2, "USD": 2,
+	"UZS": 2,
+}
+
+// CurrencyMinorUnits returns the number of minor unit digits of an ISO 4217
+// currency, and whether the currency is known.
+func CurrencyMinorUnits(code string) (int, bool) {
+	digits, ok := currencyMinorUnits[code]
+	return digits, ok
+}
+
+// Money is an amount in the minor units of Currency, e.g. cents for USD.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney pairs amount, in minor units, with a known currency.
+func NewMoney(amount int64, currency string) (Money, error) {
+	if _, ok := currencyMinorUnits[currency]; !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, currency)
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// String formats m in major units followed by the currency code, e.g.
+// "18.17 USD". Amounts in an unknown currency are printed in minor units.
+func (m Money) String() string {
+	digits := currencyMinorUnits[m.Currency]
+	abs := m.Amount
+	sign := ""
+	if abs < 0 {
+		abs, sign = -abs, "-"
+	}
+	s := strconv.FormatInt(abs, 10)
+	if digits > 0 {
+		if len(s) <= digits {
+			s = strings.Repeat("0", digits-len(s)+1) + s
+		}
+		s = s[:len(s)-digits] + "." + s[len(s)-digits:]
+	}
+	return sign + s + " " + m.Currency
+}
+
+func (p Payment) money(amount int) Money {
+	return Money{Amount: int64(amount), Currency: p.Currency}
+}
+
+// AmountMoney, GoodsTotalMoney, DeliveryCostMoney and CustomFeeMoney return
+// the payment's amounts in its currency.
+func (p Payment) AmountMoney() Money       { return p.money(p.Amount) }
+func (p Payment) GoodsTotalMoney() Money   { return p.money(p.GoodsTotal) }
+func (p Payment) DeliveryCostMoney() Money { return p.money(p.DeliveryCost) }
+func (p Payment) CustomFeeMoney() Money    { return p.money(p.CustomFee) }
+
+// ItemsTotal sums the items' total_price in the payment currency.
+func (o Order) ItemsTotal() Money {
+	total := o.Payment.money(0)
+	for _, item := range o.Items {
+		total.Amount += int64(item.TotalPrice)
+	}
+	return total
+}
+
+// FormattedAmounts are an order's amounts formatted with Money.String, for
+// clients that display them as they are.
+type FormattedAmounts struct {
+	Payment FormattedPayment `json:"payment"`
+	Items   []FormattedItem  `json:"items"`
+}
+
+type FormattedPayment struct {
+	Amount       string `json:"amount"`
+	DeliveryCost string `json:"delivery_cost"`
+	GoodsTotal   string `json:"goods_total"`
+	CustomFee    string `json:"custom_fee"`
+}
+
+type FormattedItem struct {
+	Price      string `json:"price"`
+	TotalPrice string `json:"total_price"`
+}
+
+// FormatAmounts formats every amount of o in its payment currency.
+func FormatAmounts(o Order) FormattedAmounts {
+	p := o.Payment
+	f := FormattedAmounts{
+		Payment: FormattedPayment{
+			Amount:       p.AmountMoney().String(),
+			DeliveryCost: p.DeliveryCostMoney().String(),
+			GoodsTotal:   p.GoodsTotalMoney().String(),
+			CustomFee:    p.CustomFeeMoney().String(),
+		},
+		Items: make([]FormattedItem, len(o.Items)),
+	}
+	for i, item := range o.Items {
+		f.Items[i] = FormattedItem{
+			Price:      p.money(item.Price).String(),
+			TotalPrice: p.money(item.TotalPrice).String(),
+		}
+	}
+	return f
+}
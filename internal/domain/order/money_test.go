@@ -0,0 +1,53 @@
+package order_entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{Money{1817, "USD"}, "18.17 USD"},
+		{Money{5, "EUR"}, "0.05 EUR"},
+		{Money{-150, "RUB"}, "-1.50 RUB"},
+		{Money{1817, "JPY"}, "1817 JPY"},
+		{Money{1817, "KWD"}, "1.817 KWD"},
+		{Money{1817, "XXX"}, "1817 XXX"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	if _, err := NewMoney(1, "usd"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("Expected ErrUnknownCurrency, got %v", err)
+	}
+
+	a, err := NewMoney(317, "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := a.Add(Money{1500, "USD"})
+	if err != nil || sum != (Money{1817, "USD"}) {
+		t.Errorf("Expected 18.17 USD, got %v, %v", sum, err)
+	}
+	if _, err := a.Add(Money{1, "EUR"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestFormatAmounts(t *testing.T) {
+	f := FormatAmounts(ruleTestOrder())
+	if f.Payment.Amount != "18.17 USD" || f.Payment.CustomFee != "0.00 USD" {
+		t.Errorf("Unexpected payment amounts %+v", f.Payment)
+	}
+	if len(f.Items) != 2 || f.Items[1].TotalPrice != "2.00 USD" {
+		t.Errorf("Unexpected item amounts %+v", f.Items)
+	}
+}
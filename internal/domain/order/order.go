@@ -54,4 +54,5 @@ type Order struct {
 	SmID              int       `json:"sm_id" validate:"required"`
 	DateCreated       time.Time `json:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" validate:"required"`
+	Status            string    `json:"status,omitempty"`
 }
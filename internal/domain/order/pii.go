@@ -0,0 +1,32 @@
+package order_entity
+
+// Personal data fields of an order, as named by masking policies.
+const (
+	PIIName    = "name"
+	PIIPhone   = "phone"
+	PIIZip     = "zip"
+	PIICity    = "city"
+	PIIAddress = "address"
+	PIIRegion  = "region"
+	PIIEmail   = "email"
+)
+
+// MaskPII returns the delivery with every personal data field replaced by
+// mask(field, value).
+func (d Delivery) MaskPII(mask func(field, value string) string) Delivery {
+	return Delivery{
+		Name:    mask(PIIName, d.Name),
+		Phone:   mask(PIIPhone, d.Phone),
+		Zip:     mask(PIIZip, d.Zip),
+		City:    mask(PIICity, d.City),
+		Address: mask(PIIAddress, d.Address),
+		Region:  mask(PIIRegion, d.Region),
+		Email:   mask(PIIEmail, d.Email),
+	}
+}
+
+// MaskPII returns a copy of the order with its delivery masked.
+func (o Order) MaskPII(mask func(field, value string) string) Order {
+	o.Delivery = o.Delivery.MaskPII(mask)
+	return o
+}
@@ -0,0 +1,197 @@
+package order_entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Names of the business rules checked by a RuleValidator.
+const (
+	// RuleKnownCurrency: payment.currency is in the built-in ISO 4217 table.
+	RuleKnownCurrency = "currency_is_known"
+	// RuleGoodsTotal: payment.goods_total is the sum of items' total_price.
+	RuleGoodsTotal = "goods_total_matches_items"
+	// RulePaymentAmount: payment.amount is goods_total + delivery_cost + custom_fee.
+	RulePaymentAmount = "amount_matches_totals"
+	// RuleItemTrackNumber: every item carries the order's track_number.
+	RuleItemTrackNumber = "item_track_number_matches_order"
+	// RuleKnownItemStatus: every item status is one of the ItemStatus codes.
+	RuleKnownItemStatus = "item_status_is_known"
+)
+
+// Violation is one failed check of a rule.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every violation found in an order.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return "order violates business rules: " + strings.Join(msgs, "; ")
+}
+
+// ViolatedRules returns the names of the violated rules, each once, in the
+// order they were checked.
+func (e *ValidationError) ViolatedRules() []string {
+	var rules []string
+	for _, v := range e.Violations {
+		if len(rules) == 0 || rules[len(rules)-1] != v.Rule {
+			rules = append(rules, v.Rule)
+		}
+	}
+	return rules
+}
+
+// ViolatedRules returns the rules violated according to err, or nil if err
+// doesn't wrap a *ValidationError.
+func ViolatedRules(err error) []string {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	return verr.ViolatedRules()
+}
+
+type rule struct {
+	name  string
+	check func(o Order) []Violation
+}
+
+var rules = []rule{
+	{RuleKnownCurrency, checkKnownCurrency},
+	{RuleGoodsTotal, checkGoodsTotal},
+	{RulePaymentAmount, checkPaymentAmount},
+	{RuleItemTrackNumber, checkItemTrackNumber},
+	{RuleKnownItemStatus, checkKnownItemStatus},
+}
+
+// RuleNames lists every known rule.
+func RuleNames() []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// RuleValidator checks orders against the business rules that struct tags
+// can't express. It is safe for concurrent use.
+type RuleValidator struct {
+	rules []rule
+}
+
+// DefaultRuleValidator checks every rule.
+func DefaultRuleValidator() *RuleValidator {
+	return &RuleValidator{rules: rules}
+}
+
+// NewRuleValidator checks every rule except the disabled ones. Unknown names
+// are an error, so a typo doesn't silently leave a rule on.
+func NewRuleValidator(disabled ...string) (*RuleValidator, error) {
+	off := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		off[name] = true
+	}
+	v := &RuleValidator{}
+	for _, r := range rules {
+		if off[r.name] {
+			delete(off, r.name)
+			continue
+		}
+		v.rules = append(v.rules, r)
+	}
+	for name := range off {
+		return nil, fmt.Errorf("unknown business rule %q", name)
+	}
+	return v, nil
+}
+
+// Validate returns a *ValidationError listing every violation, or nil. A nil
+// RuleValidator accepts every order.
+func (v *RuleValidator) Validate(o Order) error {
+	if v == nil {
+		return nil
+	}
+	var violations []Violation
+	for _, r := range v.rules {
+		violations = append(violations, r.check(o)...)
+	}
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func checkKnownCurrency(o Order) []Violation {
+	if _, err := NewMoney(0, o.Payment.Currency); err != nil {
+		return []Violation{{Rule: RuleKnownCurrency, Field: "payment.currency", Message: err.Error()}}
+	}
+	return nil
+}
+
+func checkGoodsTotal(o Order) []Violation {
+	goodsTotal, itemsTotal := o.Payment.GoodsTotalMoney(), o.ItemsTotal()
+	if goodsTotal == itemsTotal {
+		return nil
+	}
+	return []Violation{{
+		Rule:    RuleGoodsTotal,
+		Field:   "payment.goods_total",
+		Message: fmt.Sprintf("is %s, items total %s", goodsTotal, itemsTotal),
+	}}
+}
+
+func checkPaymentAmount(o Order) []Violation {
+	p := o.Payment
+	want := p.GoodsTotalMoney()
+	for _, m := range []Money{p.DeliveryCostMoney(), p.CustomFeeMoney()} {
+		// All amounts share the payment currency, so Add can't fail.
+		want, _ = want.Add(m)
+	}
+	if p.AmountMoney() == want {
+		return nil
+	}
+	return []Violation{{
+		Rule:    RulePaymentAmount,
+		Field:   "payment.amount",
+		Message: fmt.Sprintf("is %s, goods_total + delivery_cost + custom_fee is %s", p.AmountMoney(), want),
+	}}
+}
+
+func checkItemTrackNumber(o Order) []Violation {
+	var violations []Violation
+	for i, item := range o.Items {
+		if item.TrackNumber != o.TrackNumber {
+			violations = append(violations, Violation{
+				Rule:    RuleItemTrackNumber,
+				Field:   fmt.Sprintf("items[%d].track_number", i),
+				Message: fmt.Sprintf("is %q, order track_number is %q", item.TrackNumber, o.TrackNumber),
+			})
+		}
+	}
+	return violations
+}
+
+func checkKnownItemStatus(o Order) []Violation {
+	var violations []Violation
+	for i, item := range o.Items {
+		if _, ok := ItemStatusName(item.Status); !ok {
+			violations = append(violations, Violation{
+				Rule:    RuleKnownItemStatus,
+				Field:   fmt.Sprintf("items[%d].status", i),
+				Message: fmt.Sprintf("%d is not a known item status", item.Status),
+			})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,83 @@
+package order_entity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func ruleTestOrder() Order {
+	return Order{
+		OrderUID:    "b563feb7b2b84b6test1",
+		TrackNumber: "WBILMTESTTRACK",
+		Payment:     Payment{Currency: "USD", Amount: 1817, DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []Item{
+			{TrackNumber: "WBILMTESTTRACK", TotalPrice: 117, Status: ItemStatusCreated},
+			{TrackNumber: "WBILMTESTTRACK", TotalPrice: 200, Status: ItemStatusCreated},
+		},
+	}
+}
+
+func TestRuleValidator_AcceptsConsistentOrder(t *testing.T) {
+	v, err := NewRuleValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate(ruleTestOrder()); err != nil {
+		t.Errorf("Expected no violations, got %v", err)
+	}
+}
+
+func TestRuleValidator_ReportsEveryViolation(t *testing.T) {
+	order := ruleTestOrder()
+	order.Payment.Currency = "XXX"
+	order.Payment.GoodsTotal = 300
+	order.Items[1].TrackNumber = "OTHER"
+
+	v, err := NewRuleValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if !errors.As(v.Validate(order), &verr) {
+		t.Fatalf("Expected a ValidationError")
+	}
+	want := []string{RuleKnownCurrency, RuleGoodsTotal, RulePaymentAmount, RuleItemTrackNumber}
+	if got := verr.ViolatedRules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected rules %v, got %v", want, got)
+	}
+	if f := verr.Violations[3].Field; f != "items[1].track_number" {
+		t.Errorf("Expected the offending item to be named, got %s", f)
+	}
+}
+
+func TestRuleValidator_DisabledRules(t *testing.T) {
+	order := ruleTestOrder()
+	order.Items[0].TrackNumber = "OTHER"
+
+	v, err := NewRuleValidator(RuleItemTrackNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Validate(order); err != nil {
+		t.Errorf("Expected the disabled rule to be skipped, got %v", err)
+	}
+
+	if _, err := NewRuleValidator("no_such_rule"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+	var nilValidator *RuleValidator
+	if err := nilValidator.Validate(order); err != nil {
+		t.Errorf("Expected a nil validator to accept everything, got %v", err)
+	}
+}
+
+func TestRuleValidator_UnknownItemStatus(t *testing.T) {
+	order := ruleTestOrder()
+	order.Items[1].Status = 999
+
+	want := []string{RuleKnownItemStatus}
+	if got := ViolatedRules(DefaultRuleValidator().Validate(order)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected rules %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,103 @@
+package order_entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Order statuses. An order is created, paid, assembled, shipped and delivered;
+// it can be cancelled until it ships and returned once it has shipped.
+const (
+	StatusCreated    = "created"
+	StatusPaid       = "paid"
+	StatusAssembling = "assembling"
+	StatusShipped    = "shipped"
+	StatusDelivered  = "delivered"
+	StatusCancelled  = "cancelled"
+	StatusReturned   = "returned"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("unknown order status")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+// transitions lists the statuses each status may move to. Cancelled and
+// returned are final.
+var transitions = map[string][]string{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+	StatusCancelled:  nil,
+	StatusReturned:   nil,
+}
+
+func IsKnownStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CheckTransition reports whether an order in status from may move to status
+// to. Staying in the same status is not a transition and is rejected too.
+func CheckTransition(from, to string) error {
+	if !IsKnownStatus(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// StatusChange is an entry of an order's timeline. From is empty for the
+// status the order was created with.
+type StatusChange struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Item statuses are the numeric codes carried in item.status, one per order
+// status. 202 is the code producers have always sent for new items, so it
+// stays the created status and the later ones follow it. Items move with their
+// order: a transition sets every item to the code of the new order status.
+const (
+	ItemStatusCreated    = 202
+	ItemStatusPaid       = 203
+	ItemStatusAssembling = 204
+	ItemStatusShipped    = 205
+	ItemStatusDelivered  = 206
+	ItemStatusCancelled  = 207
+	ItemStatusReturned   = 208
+)
+
+var itemStatuses = map[int]string{
+	ItemStatusCreated:    StatusCreated,
+	ItemStatusPaid:       StatusPaid,
+	ItemStatusAssembling: StatusAssembling,
+	ItemStatusShipped:    StatusShipped,
+	ItemStatusDelivered:  StatusDelivered,
+	ItemStatusCancelled:  StatusCancelled,
+	ItemStatusReturned:   StatusReturned,
+}
+
+// ItemStatusName returns the status an item status code stands for.
+func ItemStatusName(code int) (string, bool) {
+	name, ok := itemStatuses[code]
+	return name, ok
+}
+
+// ItemStatusFor returns the code the items of an order in status carry.
+func ItemStatusFor(status string) (int, bool) {
+	for code, name := range itemStatuses {
+		if name == status {
+			return code, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+package order_entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  error
+	}{
+		{StatusCreated, StatusPaid, nil},
+		{StatusPaid, StatusAssembling, nil},
+		{StatusAssembling, StatusShipped, nil},
+		{StatusShipped, StatusDelivered, nil},
+		{StatusDelivered, StatusReturned, nil},
+		{StatusAssembling, StatusCancelled, nil},
+		{StatusShipped, StatusCancelled, ErrInvalidTransition},
+		{StatusCreated, StatusDelivered, ErrInvalidTransition},
+		{StatusCancelled, StatusPaid, ErrInvalidTransition},
+		{StatusPaid, StatusPaid, ErrInvalidTransition},
+		{StatusPaid, "lost", ErrUnknownStatus},
+	}
+	for _, tt := range tests {
+		err := CheckTransition(tt.from, tt.to)
+		if tt.wantErr == nil && err != nil {
+			t.Errorf("%s -> %s: expected no error, got %v", tt.from, tt.to, err)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.wantErr, err)
+		}
+	}
+}
+
+func TestItemStatusName(t *testing.T) {
+	if name, ok := ItemStatusName(ItemStatusShipped); !ok || name != StatusShipped {
+		t.Errorf("Expected %s, got %s", StatusShipped, name)
+	}
+	if name, _ := ItemStatusName(202); name != StatusCreated {
+		t.Errorf("Expected legacy code 202 to stay %s, got %s", StatusCreated, name)
+	}
+	if _, ok := ItemStatusName(0); ok {
+		t.Error("Expected 0 to be unknown")
+	}
+}
+
+func TestItemStatusFor(t *testing.T) {
+	for status := range transitions {
+		code, ok := ItemStatusFor(status)
+		if !ok {
+			t.Errorf("Expected an item status for %s", status)
+			continue
+		}
+		if name, _ := ItemStatusName(code); name != status {
+			t.Errorf("Expected %d to stand for %s, got %s", code, status, name)
+		}
+	}
+	if _, ok := ItemStatusFor("lost"); ok {
+		t.Error("Expected no item status for an unknown order status")
+	}
+}
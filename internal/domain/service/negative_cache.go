@@ -0,0 +1,74 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// maxNegativeEntries bounds how many unknown order_uids are remembered, so a
+// scan over random IDs can't grow the map without limit.
+const maxNegativeEntries = 10000
+
+// negativeCache remembers order_uids that were not found in Postgres for a
+// short time. It is local to the process: an order created through another
+// instance can keep answering "not found" here for up to ttl. A nil
+// *negativeCache remembers nothing.
+type negativeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func newNegativeCache(ttl time.Duration) *negativeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &negativeCache{ttl: ttl, expires: make(map[string]time.Time), now: time.Now}
+}
+
+func (c *negativeCache) has(orderUID string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires, ok := c.expires[orderUID]
+	if !ok {
+		return false
+	}
+	if !c.now().Before(expires) {
+		delete(c.expires, orderUID)
+		return false
+	}
+	return true
+}
+
+func (c *negativeCache) add(orderUID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.expires) >= maxNegativeEntries {
+		for uid, expires := range c.expires {
+			if !now.Before(expires) {
+				delete(c.expires, uid)
+			}
+		}
+		if len(c.expires) >= maxNegativeEntries {
+			return
+		}
+	}
+	c.expires[orderUID] = now.Add(c.ttl)
+}
+
+func (c *negativeCache) forget(orderUID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, orderUID)
+}
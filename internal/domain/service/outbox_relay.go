@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"testberry/internal/ports"
+	"time"
+)
+
+var outboxStats = expvar.NewMap("outbox_relay")
+
+// outboxPurgeInterval limits how often published rows are cleaned up.
+const outboxPurgeInterval = time.Minute
+
+// OutboxRelay publishes events the repository wrote to the outbox. Delivery is
+// at least once: a crash after a send but before the rows are marked published
+// sends them again on the next run. Events are keyed by order_uid, so one
+// order's events land on one partition in the order they were written.
+type OutboxRelay struct {
+	repo      ports.OutboxRepository
+	producer  ports.Producer
+	logger    ports.Logger
+	interval  time.Duration
+	batchSize int
+	retention time.Duration
+	lastPurge time.Time
+}
+
+type RelayOption func(*OutboxRelay)
+
+// WithRelayInterval sets how often the outbox is polled. A non-positive
+// interval keeps the default.
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		if interval > 0 {
+			r.interval = interval
+		}
+	}
+}
+
+// WithRelayBatchSize sets how many events are published per batch. A
+// non-positive size keeps the default.
+func WithRelayBatchSize(size int) RelayOption {
+	return func(r *OutboxRelay) {
+		if size > 0 {
+			r.batchSize = size
+		}
+	}
+}
+
+// WithRelayRetention sets how long published rows are kept before cleanup.
+func WithRelayRetention(retention time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.retention = retention
+	}
+}
+
+func NewOutboxRelay(repo ports.OutboxRepository, producer ports.Producer, logger ports.Logger, opts ...RelayOption) *OutboxRelay {
+	r := &OutboxRelay{
+		repo:      repo,
+		producer:  producer,
+		logger:    logger,
+		interval:  time.Second,
+		batchSize: 100,
+		retention: 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("Outbox relay failed", "err", err)
+		}
+		r.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes batches until the outbox is drained or a batch is
+// not fully published.
+func (r *OutboxRelay) RelayPending(ctx context.Context) error {
+	for {
+		n, err := r.repo.RelayOutbox(ctx, r.batchSize, func(msg ports.OutboxMessage) error {
+			if err := r.producer.Send(ctx, msg.OrderUID, msg.Payload); err != nil {
+				outboxStats.Add("failed", 1)
+				return err
+			}
+			outboxStats.Add("published", 1)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if n < r.batchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (r *OutboxRelay) purge(ctx context.Context) {
+	now := time.Now()
+	if now.Sub(r.lastPurge) < outboxPurgeInterval {
+		return
+	}
+	n, err := r.repo.PurgeOutbox(ctx, now.Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("Outbox cleanup failed", "err", err)
+		}
+		return
+	}
+	r.lastPurge = now
+	if n > 0 {
+		outboxStats.Add("purged", n)
+		r.logger.Info("Outbox cleaned up", "deleted", n)
+	}
+}
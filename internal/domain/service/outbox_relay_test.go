@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"testberry/internal/ports"
+	testmock "testberry/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRelay_RelayPending_PublishesByOrderUID(t *testing.T) {
+	repo := new(testmock.MockOutboxRepository)
+	producer := new(testmock.MockProducer)
+	relay := NewOutboxRelay(repo, producer, &testmock.TestLogger{}, WithRelayBatchSize(2))
+
+	first := []ports.OutboxMessage{
+		{ID: 1, OrderUID: "a", Payload: []byte(`{"n":1}`)},
+		{ID: 2, OrderUID: "b", Payload: []byte(`{"n":2}`)},
+	}
+	second := []ports.OutboxMessage{
+		{ID: 3, OrderUID: "a", Payload: []byte(`{"n":3}`)},
+	}
+	repo.On("RelayOutbox", mock.Anything, 2).Return(first, nil).Once()
+	repo.On("RelayOutbox", mock.Anything, 2).Return(second, nil).Once()
+	producer.On("Send", mock.Anything, "a", []byte(`{"n":1}`)).Return(nil).Once()
+	producer.On("Send", mock.Anything, "b", []byte(`{"n":2}`)).Return(nil).Once()
+	producer.On("Send", mock.Anything, "a", []byte(`{"n":3}`)).Return(nil).Once()
+
+	require.NoError(t, relay.RelayPending(context.Background()))
+
+	repo.AssertExpectations(t)
+	producer.AssertExpectations(t)
+}
+
+func TestOutboxRelay_RelayPending_StopsOnPublishFailure(t *testing.T) {
+	repo := new(testmock.MockOutboxRepository)
+	producer := new(testmock.MockProducer)
+	relay := NewOutboxRelay(repo, producer, &testmock.TestLogger{}, WithRelayBatchSize(1))
+
+	repo.On("RelayOutbox", mock.Anything, 1).
+		Return([]ports.OutboxMessage{{ID: 1, OrderUID: "a", Payload: []byte(`{}`)}}, nil).Once()
+	producer.On("Send", mock.Anything, "a", []byte(`{}`)).Return(errors.New("kafka down")).Once()
+
+	require.NoError(t, relay.RelayPending(context.Background()))
+
+	repo.AssertNumberOfCalls(t, "RelayOutbox", 1)
+	producer.AssertExpectations(t)
+}
+
+func TestOutboxRelay_RelayPending_RepositoryError(t *testing.T) {
+	repo := new(testmock.MockOutboxRepository)
+	producer := new(testmock.MockProducer)
+	relay := NewOutboxRelay(repo, producer, &testmock.TestLogger{})
+
+	repo.On("RelayOutbox", mock.Anything, 100).Return([]ports.OutboxMessage{}, ports.ErrTransient).Once()
+
+	err := relay.RelayPending(context.Background())
+	assert.ErrorIs(t, err, ports.ErrTransient)
+}
+
+func TestOutboxRelay_Run_PurgesPublishedRows(t *testing.T) {
+	repo := new(testmock.MockOutboxRepository)
+	producer := new(testmock.MockProducer)
+	relay := NewOutboxRelay(repo, producer, &testmock.TestLogger{},
+		WithRelayInterval(time.Hour),
+		WithRelayRetention(time.Hour),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo.On("RelayOutbox", mock.Anything, 100).Return([]ports.OutboxMessage{}, nil).Once()
+	repo.On("PurgeOutbox", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= time.Hour
+	})).Return(int64(3), nil).Run(func(mock.Arguments) { cancel() }).Once()
+
+	require.NoError(t, relay.Run(ctx))
+	repo.AssertExpectations(t)
+}
+
+func TestNewOutboxRelay_NonPositiveOptionsKeepDefaults(t *testing.T) {
+	relay := NewOutboxRelay(new(testmock.MockOutboxRepository), new(testmock.MockProducer), &testmock.TestLogger{},
+		WithRelayInterval(0), WithRelayBatchSize(0))
+
+	assert.Equal(t, time.Second, relay.interval)
+	assert.Equal(t, 100, relay.batchSize)
+}
@@ -3,55 +3,268 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
-	"testberry/internal/adapters/cache"
-	messagebrok "testberry/internal/adapters/message_brok"
-	"testberry/internal/adapters/postgres"
+	"slices"
+	"sync/atomic"
 	order_entity "testberry/internal/domain/order"
 	"testberry/internal/ports"
 	"testberry/pkg/generator"
+	"testberry/pkg/retry"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	retryStats = expvar.NewMap("consumer_retries")
+	loadStats  = expvar.NewMap("order_loads")
+)
+
+const (
+	// restoreBatchSize is how many orders Start reads from Postgres per query.
+	restoreBatchSize = 500
+	// restoreProgressEvery is how often, in orders, Start reports progress.
+	restoreProgressEvery = 10000
+	// orderLoadTimeout bounds a GetOrder load from Postgres. The load doesn't
+	// inherit the deadline of the request that started it, and every later
+	// request for the order waits on it.
+	orderLoadTimeout = 5 * time.Second
 )
 
 type Service struct {
-	repo      ports.Repository
-	cache     ports.Cache
-	consumer  ports.Consumer
-	producer  ports.Producer
-	validator *validator.Validate
-	logger    ports.Logger
+	repo        ports.Repository
+	cache       ports.Cache
+	consumer    ports.Consumer
+	producer    ports.Producer
+	validator   *validator.Validate
+	rules       *order_entity.RuleValidator
+	logger      ports.Logger
+	retryPolicy retry.Policy
+	warmup      WarmupPolicy
+	loads       singleflight.Group
+	notFound    *negativeCache
+	// customerPages is optional; without it every history page is read from
+	// the repository.
+	customerPages ports.CustomerPageCache
+	// loadTimeout overrides orderLoadTimeout when set.
+	loadTimeout time.Duration
+	// restored is set once Start has finished warming up the cache, after
+	// restoreErr holds how the warm-up failed, if it did.
+	restored   atomic.Bool
+	restoreErr error
+}
+
+var errCacheRestoring = errors.New("cache is still being restored")
+
+type Option func(*Service)
+
+// WithNegativeCacheTTL makes GetOrder remember unknown order_uids for ttl
+// instead of querying Postgres for each request. Zero disables it.
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.notFound = newNegativeCache(ttl)
+	}
+}
+
+// WithCustomerPageCache caches pages of customers' order histories in pages.
+// A nil pages leaves them uncached.
+func WithCustomerPageCache(pages ports.CustomerPageCache) Option {
+	return func(s *Service) {
+		s.customerPages = pages
+	}
+}
+
+// WithBusinessRules replaces the default check of every business rule.
+func WithBusinessRules(rules *order_entity.RuleValidator) Option {
+	return func(s *Service) {
+		s.rules = rules
+	}
 }
 
-func NewService(repo *postgres.Repository, cache *cache.Cache, consumer *messagebrok.Consumer, producer *messagebrok.Producer, logger ports.Logger) *Service {
-	return &Service{
-		repo:      repo,
-		cache:     cache,
-		consumer:  consumer,
-		producer:  producer,
-		validator: validator.New(),
-		logger:    logger,
+func WithRetryPolicy(policy retry.Policy) Option {
+	return func(s *Service) {
+		s.retryPolicy = policy
 	}
 }
 
+func NewService(repo ports.Repository, cache ports.Cache, consumer ports.Consumer, producer ports.Producer, logger ports.Logger, opts ...Option) *Service {
+	s := &Service{
+		repo:        repo,
+		cache:       cache,
+		consumer:    consumer,
+		producer:    producer,
+		validator:   validator.New(),
+		rules:       order_entity.DefaultRuleValidator(),
+		logger:      logger,
+		retryPolicy: retry.DefaultPolicy(),
+		warmup:      DefaultWarmupPolicy(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 func (s *Service) GetOrder(ctx context.Context, orderUID string) (order_entity.Order, error) {
-	s.logger.Info("GetOrderService called", "uid", orderUID)
+	s.log(ctx).Info("GetOrderService called", "order_uid", orderUID)
+	if len(orderUID) != ports.OrderUIDLength {
+		return order_entity.Order{}, fmt.Errorf("%w: must be %d characters long, got %d", ports.ErrInvalidOrderID, ports.OrderUIDLength, len(orderUID))
+	}
 	order, exists, err := s.cache.Get(ctx, orderUID)
 	if err != nil {
 		return order, err
-	}	
+	}
 	if exists {
 		return order, nil
 	}
-	order, err = s.repo.GetOrderByID(ctx, orderUID)
+	if s.notFound.has(orderUID) {
+		loadStats.Add("negative_hits", 1)
+		return order_entity.Order{}, fmt.Errorf("%w: %s", ports.ErrOrderNotFound, orderUID)
+	}
+
+	// Concurrent misses for one order share a single load. The load outlives
+	// the request that started it, so a caller going away doesn't fail the
+	// others, but has a deadline of its own so a hung query can't hold them all.
+	result := s.loads.DoChan(orderUID, func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.orderLoadTimeout())
+		defer cancel()
+		return s.loadOrder(loadCtx, orderUID)
+	})
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return order_entity.Order{}, fmt.Errorf("%w: %w", ports.ErrTimeout, ctx.Err())
+		}
+		return order_entity.Order{}, ctx.Err()
+	case res := <-result:
+		if res.Shared {
+			loadStats.Add("shared", 1)
+		}
+		order, _ := res.Val.(order_entity.Order)
+		return order, res.Err
+	}
+}
+
+// GetOrderAsOf reads the order history, bypassing the cache.
+func (s *Service) GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error) {
+	if len(orderUID) != ports.OrderUIDLength {
+		return order_entity.Order{}, fmt.Errorf("%w: must be %d characters long, got %d", ports.ErrInvalidOrderID, ports.OrderUIDLength, len(orderUID))
+	}
+	return s.repo.GetOrderAsOf(ctx, orderUID, at)
+}
+
+func (s *Service) OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error) {
+	if len(orderUID) != ports.OrderUIDLength {
+		return nil, fmt.Errorf("%w: must be %d characters long, got %d", ports.ErrInvalidOrderID, ports.OrderUIDLength, len(orderUID))
+	}
+	return s.repo.OrderTimeline(ctx, orderUID)
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (s *Service) ListOrders(ctx context.Context, query ports.OrderQuery) (ports.OrderPage, error) {
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit < 0 || query.Limit > maxPageSize {
+		return ports.OrderPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ports.ErrInvalidQuery, maxPageSize)
+	}
+	switch query.Sort {
+	case "":
+		query.Sort = ports.SortCreatedDesc
+	case ports.SortCreatedDesc, ports.SortCreatedAsc:
+	default:
+		return ports.OrderPage{}, fmt.Errorf("%w: unknown sort %q", ports.ErrInvalidQuery, query.Sort)
+	}
+	f := query.Filter
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && !f.CreatedFrom.Before(f.CreatedTo) {
+		return ports.OrderPage{}, fmt.Errorf("%w: created_from must be before created_to", ports.ErrInvalidQuery)
+	}
+	return s.repo.ListOrders(ctx, query)
+}
+
+func (s *Service) CustomerOrders(ctx context.Context, query ports.CustomerOrdersQuery) (ports.CustomerOrdersPage, error) {
+	if query.CustomerID == "" {
+		return ports.CustomerOrdersPage{}, fmt.Errorf("%w: customer id is required", ports.ErrInvalidQuery)
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit < 0 || query.Limit > maxPageSize {
+		return ports.CustomerOrdersPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ports.ErrInvalidQuery, maxPageSize)
+	}
+	if s.customerPages == nil {
+		return s.repo.ListCustomerOrders(ctx, query)
+	}
+
+	// The generation is read before the page is loaded, so a write committed
+	// meanwhile invalidates it and the page is not cached.
+	generation, err := s.customerPages.Generation(ctx, query.CustomerID)
+	if err != nil {
+		s.log(ctx).Warn("Failed to read customer orders from cache", "customer_id", query.CustomerID, "err", err)
+		return s.repo.ListCustomerOrders(ctx, query)
+	}
+	pageKey := fmt.Sprintf("%d:%d:%s", generation, query.Limit, query.Cursor)
+	page, found, err := s.customerPages.GetPage(ctx, query.CustomerID, pageKey)
+	if err != nil {
+		s.log(ctx).Warn("Failed to read customer orders from cache", "customer_id", query.CustomerID, "err", err)
+	}
+	if found {
+		return page, nil
+	}
+	page, err = s.repo.ListCustomerOrders(ctx, query)
+	if err != nil {
+		return page, err
+	}
+	if err := s.customerPages.SetPage(ctx, query.CustomerID, generation, pageKey, page); err != nil {
+		s.log(ctx).Warn("Failed to cache customer orders", "customer_id", query.CustomerID, "err", err)
+	}
+	return page, nil
+}
+
+// maxLookupResults caps the orders returned for one track number or payment ID.
+const maxLookupResults = 100
+
+func (s *Service) FindByTrackNumber(ctx context.Context, trackNumber string) ([]ports.OrderMatch, error) {
+	if trackNumber == "" {
+		return nil, fmt.Errorf("%w: track number is required", ports.ErrInvalidQuery)
+	}
+	return s.repo.FindByTrackNumber(ctx, trackNumber, maxLookupResults)
+}
+
+func (s *Service) FindByPaymentID(ctx context.Context, paymentID string) ([]ports.OrderMatch, error) {
+	if paymentID == "" {
+		return nil, fmt.Errorf("%w: payment id is required", ports.ErrInvalidQuery)
+	}
+	return s.repo.FindByPaymentID(ctx, paymentID, maxLookupResults)
+}
+
+func (s *Service) orderLoadTimeout() time.Duration {
+	if s.loadTimeout > 0 {
+		return s.loadTimeout
+	}
+	return orderLoadTimeout
+}
+
+func (s *Service) loadOrder(ctx context.Context, orderUID string) (order_entity.Order, error) {
+	loadStats.Add("db_loads", 1)
+	order, err := s.repo.GetOrderByID(ctx, orderUID)
 	if err != nil {
-		s.logger.Error("Failed GetOrderService(err in GerOrderRepo)", "err", err)
+		if errors.Is(err, ports.ErrOrderNotFound) {
+			s.notFound.add(orderUID)
+		}
+		s.log(ctx).Error("Failed GetOrderService(err in GerOrderRepo)", "order_uid", orderUID, "err", err)
 		return order, err
 	}
 	err = s.cache.Set(ctx, order)
 	if err != nil {
-		s.logger.Error("Error setting the value in the cache", "err", err)
+		s.log(ctx).Error("Error setting the value in the cache", "order_uid", orderUID, "err", err)
 		return order, err
 	}
 
@@ -60,63 +273,252 @@ func (s *Service) GetOrder(ctx context.Context, orderUID string) (order_entity.O
 
 func (s *Service) SaveOrder(ctx context.Context) error {
 	messageHandler := func(ctx context.Context, message []byte) error {
-		var order order_entity.Order
-		if err := json.Unmarshal(message, &order); err != nil {
-			s.logger.Error("Failed to unmarshal order message:", "err", err)
+		var key []byte
+		if md, ok := ports.MessageFromContext(ctx); ok {
+			key = md.Key
+		}
+		event, err := order_entity.ParseEvent(key, message)
+		if err != nil {
+			if errors.Is(err, order_entity.ErrInvalidEvent) {
+				s.log(ctx).Error("Order event isn't valid:", "err", err)
+				return &ports.MessageError{Class: ports.ErrorClassValidation, Err: err}
+			}
+			s.log(ctx).Error("Failed to unmarshal order message:", "err", err)
+			return &ports.MessageError{Class: ports.ErrorClassUnmarshal, Err: err}
+		}
+		ctx = ports.ContextWithLogFields(ctx, "order_uid", event.OrderUID)
+
+		if err := s.handleEvent(ctx, event); err != nil {
 			return err
 		}
 
+		s.log(ctx).Info("Order successfully processed:", "type", event.Type)
+		return nil
+	}
+
+	return s.consumer.Consume(ctx, messageHandler)
+}
+
+func (s *Service) handleEvent(ctx context.Context, event order_entity.Event) error {
+	var op string
+	var persist func(ctx context.Context) error
+
+	// customers lists whose history pages the event changes. Status changes don't
+	// touch the summaries. The repository reports the customer an order
+	// belonged to before an update or delete, since it may no longer be cached.
+	var customers []string
+	var previousCustomer string
+
+	switch event.Type {
+	case order_entity.EventOrderCreated, order_entity.EventOrderUpdated:
+		order := *event.Order
+		customers = append(customers, order.CustomerID)
 		if err := s.validator.Struct(order); err != nil {
-			s.logger.Error("Order isn't valid:", "err", err)
-			return err
+			s.log(ctx).Error("Order isn't valid:", "err", err)
+			return &ports.MessageError{Class: ports.ErrorClassValidation, Err: err}
 		}
-		if err := s.repo.SaveOrder(ctx, order); err != nil {
-			s.logger.Error("Order not saved to database:", "err", err)
+		if err := s.rules.Validate(order); err != nil {
+			s.log(ctx).Error("Order violates business rules:", "rules", order_entity.ViolatedRules(err), "err", err)
+			return &ports.MessageError{Class: ports.ErrorClassValidation, Err: err}
+		}
+		if event.Type == order_entity.EventOrderCreated {
+			op = "repo.SaveOrder"
+			persist = func(ctx context.Context) error { return s.repo.SaveOrder(ctx, order) }
+		} else {
+			op = "repo.UpdateOrder"
+			persist = func(ctx context.Context) (err error) {
+				previousCustomer, err = s.repo.UpdateOrder(ctx, order)
+				return err
+			}
+		}
+	case order_entity.EventOrderStatusChanged:
+		if !order_entity.IsKnownStatus(event.Status) {
+			err := fmt.Errorf("%w: %q", order_entity.ErrUnknownStatus, event.Status)
+			s.log(ctx).Error("Order status isn't valid:", "err", err)
+			return &ports.MessageError{Class: ports.ErrorClassValidation, Err: err}
+		}
+		op = "repo.UpdateOrderStatus"
+		persist = func(ctx context.Context) error { return s.repo.UpdateOrderStatus(ctx, event.OrderUID, event.Status) }
+	case order_entity.EventOrderCancelled:
+		op = "repo.CancelOrder"
+		persist = func(ctx context.Context) error { return s.repo.CancelOrder(ctx, event.OrderUID) }
+	case order_entity.EventOrderDeleted:
+		op = "repo.DeleteOrder"
+		persist = func(ctx context.Context) (err error) {
+			previousCustomer, err = s.repo.DeleteOrder(ctx, event.OrderUID)
 			return err
 		}
-		if err := s.cache.Set(ctx, order); err != nil {
-			s.logger.Error("Order not saved to cache:", "err", err)
+	}
+
+	if err := s.withRetry(ctx, op, event.OrderUID, persist); err != nil {
+		s.log(ctx).Error("Order not saved to database:", "type", event.Type, "err", err)
+		class := ports.ErrorClassPersist
+		switch {
+		case errors.Is(err, ports.ErrTransient):
+			class = ports.ErrorClassTransient
+		case errors.Is(err, ports.ErrOrderConflict):
+			class = ports.ErrorClassConflict
+		case errors.Is(err, ports.ErrOrderNotFound):
+			class = ports.ErrorClassNotFound
+		case errors.Is(err, order_entity.ErrInvalidTransition):
+			class = ports.ErrorClassTransition
 		}
+		return &ports.MessageError{Class: class, Err: err}
+	}
+	s.invalidateCustomerPages(ctx, append(customers, previousCustomer))
 
-		s.logger.Info("Order successfully processed:", "uid", order.OrderUID)
+	if event.Type == order_entity.EventOrderDeleted {
+		if err := s.withRetry(ctx, "cache.Delete", event.OrderUID, func(ctx context.Context) error {
+			return s.cache.Delete(ctx, event.OrderUID)
+		}); err != nil {
+			s.log(ctx).Error("Order not removed from cache:", "err", err)
+		}
 		return nil
 	}
+	s.notFound.forget(event.OrderUID)
+	s.refreshCache(ctx, event.OrderUID)
+	return nil
+}
 
-	return s.consumer.Consume(ctx, messageHandler)
+func (s *Service) invalidateCustomerPages(ctx context.Context, customers []string) {
+	if s.customerPages == nil {
+		return
+	}
+	for i, customerID := range customers {
+		if customerID == "" || slices.Contains(customers[:i], customerID) {
+			continue
+		}
+		if err := s.withRetry(ctx, "cache.InvalidateCustomer", customerID, func(ctx context.Context) error {
+			return s.customerPages.InvalidateCustomer(ctx, customerID)
+		}); err != nil {
+			s.log(ctx).Error("Customer orders not invalidated:", "customer_id", customerID, "err", err)
+		}
+	}
+}
+
+// refreshCache caches the order as stored, so the cached copy carries the
+// status and version kept by the repository. If the order can't be reloaded
+// the cached copy is dropped instead of being left stale.
+func (s *Service) refreshCache(ctx context.Context, orderUID string) {
+	err := s.withRetry(ctx, "cache.Set", orderUID, func(ctx context.Context) error {
+		order, err := s.repo.GetOrderByID(ctx, orderUID)
+		if err != nil {
+			return err
+		}
+		return s.cache.Set(ctx, order)
+	})
+	if err == nil {
+		return
+	}
+	s.log(ctx).Error("Order not saved to cache:", "err", err)
+	if err := s.cache.Delete(ctx, orderUID); err != nil {
+		s.log(ctx).Error("Failed to invalidate cached order:", "err", err)
+	}
+}
+
+// withRetry runs op under the service retry policy, retrying only errors marked
+// as ports.ErrTransient.
+func (s *Service) withRetry(ctx context.Context, op, orderUID string, fn func(ctx context.Context) error) error {
+	attempts, err := retry.Do(ctx, s.retryPolicy,
+		func(err error) bool { return errors.Is(err, ports.ErrTransient) },
+		func(attempt int, delay time.Duration, err error) {
+			retryStats.Add("retries", 1)
+			s.log(ctx).Warn("Transient failure, retrying", "op", op, "uid", orderUID, "attempt", attempt, "delay", delay, "err", err)
+		},
+		fn,
+	)
+	switch {
+	case err == nil && attempts > 1:
+		retryStats.Add("recovered", 1)
+		s.log(ctx).Info("Recovered after retry", "op", op, "uid", orderUID, "attempts", attempts)
+	case err == nil:
+	case ctx.Err() != nil:
+		retryStats.Add("aborted", 1)
+		s.log(ctx).Warn("Retry aborted, context cancelled", "op", op, "uid", orderUID, "attempts", attempts)
+	case errors.Is(err, ports.ErrTransient):
+		retryStats.Add("exhausted", 1)
+		s.log(ctx).Error("Retry budget exhausted", "op", op, "uid", orderUID, "attempts", attempts, "err", err)
+	}
+	return err
 }
 
 func (s *Service) SendRandomOrder(ctx context.Context) error {
 	order := s.generateRandomOrder()
 	orderJSON, err := json.Marshal(order)
 	if err != nil {
-		s.logger.Error("Failed to marshal order:", "err", err)
+		s.log(ctx).Error("Failed to marshal order:", "err", err)
 		return fmt.Errorf("failed to marshal order: %w", err)
 	}
-	if err := s.producer.Send(order.OrderUID, orderJSON); err != nil {
-		s.logger.Error("Failed to send order to producer:", "err", err)
+	if err := s.producer.Send(ctx, order.OrderUID, orderJSON); err != nil {
+		s.log(ctx).Error("Failed to send order to producer:", "err", err)
 		return fmt.Errorf("failed to send order to producer: %w", err)
 	}
 
-	s.logger.Info("Order sent successfully:", "uid", order.OrderUID)
+	s.log(ctx).Info("Order sent successfully:", "order_uid", order.OrderUID)
 	return nil
 }
 
+// log returns the service logger with the correlation fields carried by ctx.
+func (s *Service) log(ctx context.Context) ports.Logger {
+	return ports.LoggerFromContext(ctx, s.logger)
+}
+
 func (s *Service) generateRandomOrder() order_entity.Order {
 	return generator.GenerateRandomOrder(time.Now().UnixNano())
 }
 
-func (s *Service) Start(ctx context.Context) error {
-	orders, err := s.repo.RestoreCache(ctx)
-	if err != nil {
+func (s *Service) Start(ctx context.Context) (err error) {
+	defer func() {
+		s.restoreErr = err
+		s.restored.Store(true)
+	}()
+	opts := ports.StreamOptions{BatchSize: restoreBatchSize}
+	if err := s.warmup.Validate(); err != nil {
 		return err
 	}
+	switch s.warmup.Mode {
+	case WarmupNone:
+		s.log(ctx).Info("Cache warm-up disabled")
+		return nil
+	case WarmupRecent:
+		opts.Limit = s.warmup.Limit
+	case WarmupSince:
+		opts.Since = time.Now().UTC().Add(-s.warmup.Since)
+	}
 
-	for _, order := range orders {
-		if err := s.cache.Set(ctx, order); err != nil {
-			s.logger.Error("Failed to restore order to cache:", "err", err)
+	restored, failed := 0, 0
+	nextReport := restoreProgressEvery
+	err = s.repo.StreamOrders(ctx, opts, func(batch []order_entity.Order) error {
+		for _, order := range batch {
+			if err := s.cache.Set(ctx, order); err != nil {
+				s.log(ctx).Error("Failed to restore order to cache:", "err", err)
+				failed++
+				continue
+			}
+			restored++
+		}
+		if restored+failed >= nextReport {
+			s.log(ctx).Info("Restoring cache", "restored", restored, "failed", failed)
+			nextReport += restoreProgressEvery
 		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
 	}
 
-	s.logger.Info("Cache restored successfully!", "OK", 1)
+	s.log(ctx).Info("Cache restored successfully!", "restored", restored, "failed", failed)
+	return nil
+}
+
+// CacheRestored fails until Start has finished, and afterwards if the
+// warm-up failed, for readiness probes.
+func (s *Service) CacheRestored(_ context.Context) error {
+	if !s.restored.Load() {
+		return errCacheRestoring
+	}
+	if s.restoreErr != nil {
+		return fmt.Errorf("cache restore failed: %w", s.restoreErr)
+	}
 	return nil
 }
@@ -5,11 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 
 	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	"testberry/pkg/retry"
 
 	testmock "testberry/pkg/test"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
@@ -48,8 +53,8 @@ func TestService_GetOrder_CachMiss(t *testing.T) {
 	mockLogger := &testmock.TestLogger{}
 
 	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
-	mockRepo.On("GetOrderByID", ctx, orderUID).Return(expectedOrder, nil)
-	mockCache.On("Set", ctx, expectedOrder).Return(nil)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).Return(expectedOrder, nil)
+	mockCache.On("Set", mock.Anything, expectedOrder).Return(nil)
 
 	s := &Service{
 		repo:   mockRepo,
@@ -94,7 +99,7 @@ func TestService_GetOrder_Cache_Miss_DB_Error(t *testing.T) {
 	mockLogger := &testmock.TestLogger{}
 
 	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
-	mockRepo.On("GetOrderByID", ctx, orderUID).Return(order_entity.Order{}, fmt.Errorf("order with UID %s not found", orderUID))
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).Return(order_entity.Order{}, fmt.Errorf("order with UID %s not found", orderUID))
 	s := &Service{
 		repo:   mockRepo,
 		cache:  mockCache,
@@ -117,8 +122,8 @@ func TestService_GetOrder_CacheMiss_SetError(t *testing.T) {
 	mockLogger := &testmock.TestLogger{}
 
 	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
-	mockRepo.On("GetOrderByID", ctx, orderUID).Return(expectedOrder, nil)
-	mockCache.On("Set", ctx, expectedOrder).Return(errors.New("cache set error"))
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).Return(expectedOrder, nil)
+	mockCache.On("Set", mock.Anything, expectedOrder).Return(errors.New("cache set error"))
 
 	s := &Service{
 		repo:   mockRepo,
@@ -209,7 +214,7 @@ func TestService_SaveOrder_SaveToDBError(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("SaveOrder", ctx, mock.Anything).Return(errors.New("db error"))
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).Return(errors.New("db error"))
 	validate := validator.New()
 	service := &Service{
 		repo:      mockRepo,
@@ -223,3 +228,791 @@ func TestService_SaveOrder_SaveToDBError(t *testing.T) {
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestService_SaveOrder_RetriesTransientDBError(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockLogger := &testmock.TestLogger{}
+
+	orderJSON, err := json.Marshal(testmock.Test_order)
+	require.NoError(t, err)
+
+	mockConsumer := &testmock.MockConsumer{
+		ConsumeFunc: func(ctx context.Context, handler func(context.Context, []byte) error) error {
+			return handler(ctx, orderJSON)
+		},
+	}
+
+	transientErr := fmt.Errorf("%w: connection reset", ports.ErrTransient)
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).Return(transientErr).Twice()
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).Return(nil).Once()
+	mockRepo.On("GetOrderByID", mock.Anything, testmock.Test_order.OrderUID).Return(testmock.Test_order, nil)
+	mockCache.On("Set", mock.Anything, testmock.Test_order).Return(nil)
+
+	service := &Service{
+		repo:        mockRepo,
+		cache:       mockCache,
+		logger:      mockLogger,
+		consumer:    mockConsumer,
+		validator:   validator.New(),
+		retryPolicy: retry.Policy{InitialInterval: time.Millisecond, Multiplier: 2, MaxAttempts: 3},
+	}
+
+	err = service.SaveOrder(ctx)
+	require.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "SaveOrder", 3)
+	mockCache.AssertExpectations(t)
+}
+
+func TestService_SaveOrder_TransientRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockLogger := &testmock.TestLogger{}
+
+	orderJSON, err := json.Marshal(testmock.Test_order)
+	require.NoError(t, err)
+
+	var handlerErr error
+	mockConsumer := &testmock.MockConsumer{
+		ConsumeFunc: func(ctx context.Context, handler func(context.Context, []byte) error) error {
+			handlerErr = handler(ctx, orderJSON)
+			return nil
+		},
+	}
+
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).Return(fmt.Errorf("%w: connection refused", ports.ErrTransient))
+
+	service := &Service{
+		repo:        mockRepo,
+		cache:       mockCache,
+		logger:      mockLogger,
+		consumer:    mockConsumer,
+		validator:   validator.New(),
+		retryPolicy: retry.Policy{InitialInterval: time.Millisecond, Multiplier: 2, MaxAttempts: 3},
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	mockRepo.AssertNumberOfCalls(t, "SaveOrder", 3)
+
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassTransient, msgErr.Class)
+}
+
+func TestService_SaveOrder_PermanentDBErrorNotRetried(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockLogger := &testmock.TestLogger{}
+
+	orderJSON, err := json.Marshal(testmock.Test_order)
+	require.NoError(t, err)
+
+	var handlerErr error
+	mockConsumer := &testmock.MockConsumer{
+		ConsumeFunc: func(ctx context.Context, handler func(context.Context, []byte) error) error {
+			handlerErr = handler(ctx, orderJSON)
+			return nil
+		},
+	}
+
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).Return(errors.New("duplicate key value"))
+
+	service := &Service{
+		repo:        mockRepo,
+		cache:       mockCache,
+		logger:      mockLogger,
+		consumer:    mockConsumer,
+		validator:   validator.New(),
+		retryPolicy: retry.Policy{InitialInterval: time.Millisecond, Multiplier: 2, MaxAttempts: 3},
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	mockRepo.AssertNumberOfCalls(t, "SaveOrder", 1)
+
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassPersist, msgErr.Class)
+}
+
+func TestService_SaveOrder_StopsRetryingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockLogger := &testmock.TestLogger{}
+
+	orderJSON, err := json.Marshal(testmock.Test_order)
+	require.NoError(t, err)
+
+	var handlerErr error
+	mockConsumer := &testmock.MockConsumer{
+		ConsumeFunc: func(ctx context.Context, handler func(context.Context, []byte) error) error {
+			handlerErr = handler(ctx, orderJSON)
+			return nil
+		},
+	}
+
+	mockRepo.On("SaveOrder", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { cancel() }).
+		Return(fmt.Errorf("%w: connection refused", ports.ErrTransient))
+
+	service := &Service{
+		repo:        mockRepo,
+		cache:       mockCache,
+		logger:      mockLogger,
+		consumer:    mockConsumer,
+		validator:   validator.New(),
+		retryPolicy: retry.Policy{InitialInterval: time.Hour, Multiplier: 2, MaxAttempts: 10},
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	mockRepo.AssertNumberOfCalls(t, "SaveOrder", 1)
+	assert.ErrorIs(t, handlerErr, context.Canceled)
+}
+
+func consumeOne(ctx context.Context, key string, value []byte, handlerErr *error) *testmock.MockConsumer {
+	return &testmock.MockConsumer{
+		ConsumeFunc: func(_ context.Context, handler func(context.Context, []byte) error) error {
+			msgCtx := ports.ContextWithMessage(ctx, ports.MessageMetadata{Topic: "orders", Key: []byte(key)})
+			*handlerErr = handler(msgCtx, value)
+			return nil
+		},
+	}
+}
+
+func TestService_SaveOrder_UpdatedEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	updated := testmock.Test_order
+	updated.Delivery.City = "Haifa"
+	event, err := json.Marshal(order_entity.Event{
+		Type:     order_entity.EventOrderUpdated,
+		Version:  order_entity.EventVersion,
+		OrderUID: updated.OrderUID,
+		Order:    &updated,
+	})
+	require.NoError(t, err)
+
+	var handlerErr error
+	mockRepo.On("UpdateOrder", mock.Anything, mock.MatchedBy(func(o order_entity.Order) bool {
+		return o.Delivery.City == "Haifa"
+	})).Return(testmock.Test_order.CustomerID, nil)
+	mockRepo.On("GetOrderByID", mock.Anything, updated.OrderUID).Return(updated, nil)
+	mockCache.On("Set", mock.Anything, updated).Return(nil)
+
+	service := &Service{
+		repo:      mockRepo,
+		cache:     mockCache,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, updated.OrderUID, event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	require.NoError(t, handlerErr)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestService_SaveOrder_StatusChangedEvent(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	uid := testmock.Test_order.OrderUID
+
+	var handlerErr error
+	event := []byte(`{"type":"order.status_changed","version":1,"order_uid":"` + uid + `","status":"shipped"}`)
+	mockRepo.On("UpdateOrderStatus", mock.Anything, uid, "shipped").Return(nil)
+	mockRepo.On("GetOrderByID", mock.Anything, uid).Return(testmock.Test_order, nil)
+	mockCache.On("Set", mock.Anything, testmock.Test_order).Return(nil)
+
+	service := &Service{
+		repo:      mockRepo,
+		cache:     mockCache,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, uid, event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	require.NoError(t, handlerErr)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestService_SaveOrder_CancelledEventForUnknownOrder(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	uid := "unknownunknownunknow"
+
+	var handlerErr error
+	event := []byte(`{"type":"order.cancelled","version":1,"order_uid":"` + uid + `"}`)
+	mockRepo.On("CancelOrder", mock.Anything, uid).Return(fmt.Errorf("%w: %s", ports.ErrOrderNotFound, uid))
+
+	service := &Service{
+		repo:      mockRepo,
+		cache:     mockCache,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, uid, event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassNotFound, msgErr.Class)
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestService_SaveOrder_TombstoneDeletesOrder(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockPages := new(testmock.MockCustomerPageCache)
+	uid := testmock.Test_order.OrderUID
+
+	var handlerErr error
+	// The repository logs the message's order_uid, carried by the context.
+	withOrderUID := mock.MatchedBy(func(ctx context.Context) bool {
+		return assert.ObjectsAreEqual([]interface{}{"order_uid", uid}, ports.LogFields(ctx))
+	})
+	mockRepo.On("DeleteOrder", withOrderUID, uid).Return(testmock.Test_order.CustomerID, nil)
+	mockCache.On("Delete", mock.Anything, uid).Return(nil)
+	mockPages.On("InvalidateCustomer", mock.Anything, testmock.Test_order.CustomerID).Return(nil).Once()
+
+	service := &Service{
+		repo:          mockRepo,
+		cache:         mockCache,
+		customerPages: mockPages,
+		logger:        &testmock.TestLogger{},
+		consumer:      consumeOne(ctx, uid, nil, &handlerErr),
+		validator:     validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	require.NoError(t, handlerErr)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockPages.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestService_SaveOrder_UnknownEventType(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+
+	var handlerErr error
+	event := []byte(`{"type":"order.teleported","version":1,"order_uid":"12345678901234567890"}`)
+	service := &Service{
+		repo:      mockRepo,
+		cache:     new(testmock.MockCache),
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, "12345678901234567890", event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassValidation, msgErr.Class)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Start_RestoresCacheInBatches(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	orders := make([]order_entity.Order, restoreBatchSize+1)
+	for i := range orders {
+		orders[i] = testmock.Test_order
+		orders[i].OrderUID = fmt.Sprintf("%020d", i)
+	}
+	mockRepo.On("StreamOrders", ctx, ports.StreamOptions{BatchSize: restoreBatchSize}).Return(orders, nil)
+	mockCache.On("Set", ctx, orders[3]).Return(errors.New("redis down")).Once()
+	mockCache.On("Set", ctx, mock.Anything).Return(nil)
+
+	service := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupAll}}
+
+	require.NoError(t, service.Start(ctx))
+	mockCache.AssertNumberOfCalls(t, "Set", len(orders))
+}
+
+func TestService_Start_RepositoryError(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockRepo.On("StreamOrders", ctx, mock.Anything).Return([]order_entity.Order{}, ports.ErrTransient)
+
+	service := &Service{repo: mockRepo, cache: new(testmock.MockCache), logger: &testmock.TestLogger{}, warmup: DefaultWarmupPolicy()}
+
+	assert.ErrorIs(t, service.Start(ctx), ports.ErrTransient)
+	assert.ErrorIs(t, service.CacheRestored(ctx), ports.ErrTransient)
+}
+
+func TestService_Start_WarmupPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	recent := new(testmock.MockRepository)
+	recent.On("StreamOrders", ctx, ports.StreamOptions{BatchSize: restoreBatchSize, Limit: 50}).Return([]order_entity.Order{}, nil)
+	service := &Service{repo: recent, logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupRecent, Limit: 50}}
+	require.NoError(t, service.Start(ctx))
+	recent.AssertExpectations(t)
+
+	since := new(testmock.MockRepository)
+	since.On("StreamOrders", ctx, mock.MatchedBy(func(opts ports.StreamOptions) bool {
+		return opts.Limit == 0 && time.Since(opts.Since) >= time.Hour && time.Since(opts.Since) < 2*time.Hour
+	})).Return([]order_entity.Order{}, nil)
+	service = &Service{repo: since, logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupSince, Since: time.Hour}}
+	require.NoError(t, service.Start(ctx))
+	since.AssertExpectations(t)
+
+	none := new(testmock.MockRepository)
+	service = &Service{repo: none, logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupNone}}
+	require.NoError(t, service.Start(ctx))
+	none.AssertNotCalled(t, "StreamOrders", mock.Anything, mock.Anything)
+}
+
+func TestWarmupPolicy_Validate(t *testing.T) {
+	assert.NoError(t, DefaultWarmupPolicy().Validate())
+	assert.NoError(t, WarmupPolicy{Mode: WarmupAll}.Validate())
+	assert.NoError(t, WarmupPolicy{Mode: WarmupNone}.Validate())
+	assert.Error(t, WarmupPolicy{Mode: WarmupRecent, Limit: 0}.Validate())
+	assert.Error(t, WarmupPolicy{Mode: WarmupRecent, Limit: -1}.Validate())
+	assert.Error(t, WarmupPolicy{Mode: WarmupSince}.Validate())
+
+	// Start refuses an unbounded recent warm-up instead of loading every order.
+	repo := new(testmock.MockRepository)
+	service := &Service{repo: repo, logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupRecent}}
+	assert.Error(t, service.Start(context.Background()))
+	repo.AssertNotCalled(t, "StreamOrders", mock.Anything, mock.Anything)
+}
+
+func TestParseWarmupMode(t *testing.T) {
+	for _, s := range []string{"all", "recent", "since", "none"} {
+		m, err := ParseWarmupMode(s)
+		assert.NoError(t, err)
+		assert.Equal(t, WarmupMode(s), m)
+	}
+	_, err := ParseWarmupMode("everything")
+	assert.Error(t, err)
+}
+
+func TestService_GetOrder_ConcurrentMissesShareOneLoad(t *testing.T) {
+	ctx := context.Background()
+	orderUID := testmock.Test_order.OrderUID
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	release := make(chan struct{})
+	var misses atomic.Int32
+	mockCache.On("Get", ctx, orderUID).Run(func(mock.Arguments) { misses.Add(1) }).Return(order_entity.Order{}, false, nil)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).
+		Run(func(mock.Arguments) { <-release }).
+		Return(testmock.Test_order, nil).Once()
+	mockCache.On("Set", mock.Anything, testmock.Test_order).Return(nil).Once()
+
+	s := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]order_entity.Order, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = s.GetOrder(ctx, orderUID)
+		}(i)
+	}
+	require.Eventually(t, func() bool { return misses.Load() == callers }, time.Second, time.Millisecond)
+	// Give the last callers time to join the load in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, testmock.Test_order, results[i])
+	}
+	mockRepo.AssertNumberOfCalls(t, "GetOrderByID", 1)
+	mockCache.AssertNumberOfCalls(t, "Set", 1)
+}
+
+func TestService_GetOrder_WaiterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	orderUID := testmock.Test_order.OrderUID
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	release := make(chan struct{})
+	defer close(release)
+	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).Run(func(mock.Arguments) { <-release }).Return(testmock.Test_order, nil)
+	mockCache.On("Set", mock.Anything, testmock.Test_order).Return(nil)
+
+	s := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}}
+	cancel()
+
+	_, err := s.GetOrder(ctx, orderUID)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestService_GetOrder_HungLoadTimesOut(t *testing.T) {
+	ctx := context.Background()
+	orderUID := testmock.Test_order.OrderUID
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(order_entity.Order{}, context.DeadlineExceeded)
+
+	s := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}, loadTimeout: 20 * time.Millisecond}
+
+	// The caller has no deadline; the load gives up on its own, and the next
+	// request starts a new load instead of joining the hung one.
+	for i := 0; i < 2; i++ {
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.GetOrder(ctx, orderUID)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("GetOrder did not return after the load timeout")
+		}
+	}
+	mockRepo.AssertNumberOfCalls(t, "GetOrderByID", 2)
+}
+
+func TestService_GetOrder_NegativeCache(t *testing.T) {
+	ctx := context.Background()
+	orderUID := "unknownorderuid00000"
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+
+	mockCache.On("Get", ctx, orderUID).Return(order_entity.Order{}, false, nil)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).
+		Return(order_entity.Order{}, fmt.Errorf("%w: %s", ports.ErrOrderNotFound, orderUID)).Once()
+
+	s := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}, notFound: newNegativeCache(time.Minute)}
+
+	for i := 0; i < 3; i++ {
+		_, err := s.GetOrder(ctx, orderUID)
+		assert.ErrorIs(t, err, ports.ErrOrderNotFound)
+	}
+	mockRepo.AssertNumberOfCalls(t, "GetOrderByID", 1)
+
+	// Storing the order makes it visible right away.
+	s.notFound.forget(orderUID)
+	mockRepo.On("GetOrderByID", mock.Anything, orderUID).Return(testmock.Test_order, nil).Once()
+	mockCache.On("Set", mock.Anything, testmock.Test_order).Return(nil).Once()
+	_, err := s.GetOrder(ctx, orderUID)
+	assert.NoError(t, err)
+}
+
+func TestNegativeCache_Expires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newNegativeCache(5 * time.Second)
+	c.now = func() time.Time { return now }
+
+	c.add("a")
+	assert.True(t, c.has("a"))
+	now = now.Add(5 * time.Second)
+	assert.False(t, c.has("a"))
+
+	var disabled *negativeCache
+	disabled.add("a")
+	assert.False(t, disabled.has("a"))
+	assert.Nil(t, newNegativeCache(0))
+}
+
+func TestService_GetOrder_InvalidOrderID(t *testing.T) {
+	mockCache := new(testmock.MockCache)
+	s := &Service{repo: new(testmock.MockRepository), cache: mockCache, logger: &testmock.TestLogger{}}
+
+	_, err := s.GetOrder(context.Background(), "123")
+	assert.ErrorIs(t, err, ports.ErrInvalidOrderID)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestService_ListOrders(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	s := &Service{repo: mockRepo, logger: &testmock.TestLogger{}}
+
+	mockRepo.On("ListOrders", ctx, ports.OrderQuery{Sort: ports.SortCreatedDesc, Limit: defaultPageSize}).
+		Return(ports.OrderPage{Orders: []order_entity.Order{testmock.Test_order}}, nil)
+	page, err := s.ListOrders(ctx, ports.OrderQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Orders, 1)
+
+	invalid := []ports.OrderQuery{
+		{Limit: maxPageSize + 1},
+		{Limit: -1},
+		{Sort: "price"},
+		{Filter: ports.OrderFilter{CreatedFrom: time.Now(), CreatedTo: time.Now().Add(-time.Hour)}},
+	}
+	for _, q := range invalid {
+		_, err := s.ListOrders(ctx, q)
+		assert.ErrorIs(t, err, ports.ErrInvalidQuery, "%+v", q)
+	}
+	mockRepo.AssertNumberOfCalls(t, "ListOrders", 1)
+}
+
+func TestService_CustomerOrders(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockPages := new(testmock.MockCustomerPageCache)
+	s := &Service{repo: mockRepo, customerPages: mockPages, logger: &testmock.TestLogger{}}
+
+	query := ports.CustomerOrdersQuery{CustomerID: "test", Limit: defaultPageSize}
+	page := ports.CustomerOrdersPage{Orders: []ports.OrderSummary{{OrderUID: testmock.Test_order.OrderUID}}}
+	mockPages.On("Generation", ctx, "test").Return(int64(0), nil).Times(2)
+	mockPages.On("GetPage", ctx, "test", "0:20:").Return(ports.CustomerOrdersPage{}, false, nil).Once()
+	mockRepo.On("ListCustomerOrders", ctx, query).Return(page, nil).Once()
+	mockPages.On("SetPage", ctx, "test", int64(0), "0:20:", page).Return(nil).Once()
+
+	got, err := s.CustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, page, got)
+
+	mockPages.On("GetPage", ctx, "test", "0:20:").Return(page, true, nil).Once()
+	got, err = s.CustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "test"})
+	require.NoError(t, err)
+	assert.Equal(t, page, got)
+
+	// After an invalidation, pages are read and stored under the new generation.
+	mockPages.On("Generation", ctx, "test").Return(int64(1), nil).Once()
+	mockPages.On("GetPage", ctx, "test", "1:20:").Return(ports.CustomerOrdersPage{}, false, nil).Once()
+	mockRepo.On("ListCustomerOrders", ctx, query).Return(page, nil).Once()
+	mockPages.On("SetPage", ctx, "test", int64(1), "1:20:", page).Return(nil).Once()
+	_, err = s.CustomerOrders(ctx, ports.CustomerOrdersQuery{CustomerID: "test"})
+	require.NoError(t, err)
+
+	for _, q := range []ports.CustomerOrdersQuery{{}, {CustomerID: "test", Limit: maxPageSize + 1}} {
+		_, err := s.CustomerOrders(ctx, q)
+		assert.ErrorIs(t, err, ports.ErrInvalidQuery, "%+v", q)
+	}
+	mockRepo.AssertExpectations(t)
+	mockPages.AssertExpectations(t)
+}
+
+func TestService_CustomerOrders_CacheErrorFallsBackToRepository(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockPages := new(testmock.MockCustomerPageCache)
+	s := &Service{repo: mockRepo, customerPages: mockPages, logger: &testmock.TestLogger{}}
+
+	query := ports.CustomerOrdersQuery{CustomerID: "test", Limit: 5, Cursor: "abc"}
+	cacheErr := fmt.Errorf("%w: %w: redis down", ports.ErrTransient, ports.ErrDependencyUnavailable)
+	mockPages.On("Generation", ctx, "test").Return(int64(3), nil).Once()
+	mockPages.On("GetPage", ctx, "test", "3:5:abc").Return(ports.CustomerOrdersPage{}, false, cacheErr)
+	mockRepo.On("ListCustomerOrders", ctx, query).Return(ports.CustomerOrdersPage{}, nil)
+	mockPages.On("SetPage", ctx, "test", int64(3), "3:5:abc", ports.CustomerOrdersPage{}).Return(cacheErr)
+
+	_, err := s.CustomerOrders(ctx, query)
+	require.NoError(t, err)
+
+	// Without a generation the page is neither read nor stored.
+	mockPages.On("Generation", ctx, "test").Return(int64(0), cacheErr).Once()
+	_, err = s.CustomerOrders(ctx, query)
+	require.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "ListCustomerOrders", 2)
+	mockPages.AssertNumberOfCalls(t, "GetPage", 1)
+	mockPages.AssertNumberOfCalls(t, "SetPage", 1)
+}
+
+func TestService_SaveOrder_InvalidatesCustomerPages(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	mockPages := new(testmock.MockCustomerPageCache)
+
+	updated := testmock.Test_order
+	updated.CustomerID = "new-customer"
+	event, err := json.Marshal(order_entity.Event{
+		Type:     order_entity.EventOrderUpdated,
+		Version:  order_entity.EventVersion,
+		OrderUID: updated.OrderUID,
+		Order:    &updated,
+	})
+	require.NoError(t, err)
+
+	// The previous customer comes from the repository, even when the order is
+	// no longer cached.
+	var handlerErr error
+	mockRepo.On("UpdateOrder", mock.Anything, mock.MatchedBy(func(o order_entity.Order) bool {
+		return o.CustomerID == "new-customer"
+	})).Return(testmock.Test_order.CustomerID, nil)
+	mockPages.On("InvalidateCustomer", mock.Anything, "new-customer").Return(nil).Once()
+	mockPages.On("InvalidateCustomer", mock.Anything, testmock.Test_order.CustomerID).Return(nil).Once()
+	mockRepo.On("GetOrderByID", mock.Anything, updated.OrderUID).Return(updated, nil)
+	mockCache.On("Set", mock.Anything, updated).Return(nil)
+
+	service := &Service{
+		repo:          mockRepo,
+		cache:         mockCache,
+		customerPages: mockPages,
+		logger:        &testmock.TestLogger{},
+		consumer:      consumeOne(ctx, updated.OrderUID, event, &handlerErr),
+		validator:     validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	require.NoError(t, handlerErr)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	mockPages.AssertExpectations(t)
+	// Event handling must not skew the cache hit ratio of GetOrder.
+	mockCache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestService_FindOrders(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	s := &Service{repo: mockRepo, logger: &testmock.TestLogger{}}
+
+	matches := []ports.OrderMatch{{OrderUID: testmock.Test_order.OrderUID, MatchedBy: []string{ports.MatchOrderTrackNumber}}}
+	mockRepo.On("FindByTrackNumber", ctx, "WBILMTESTTRACK", maxLookupResults).Return(matches, nil)
+	mockRepo.On("FindByPaymentID", ctx, "req123", maxLookupResults).Return([]ports.OrderMatch(nil), nil)
+
+	got, err := s.FindByTrackNumber(ctx, "WBILMTESTTRACK")
+	require.NoError(t, err)
+	assert.Equal(t, matches, got)
+	got, err = s.FindByPaymentID(ctx, "req123")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = s.FindByTrackNumber(ctx, "")
+	assert.ErrorIs(t, err, ports.ErrInvalidQuery)
+	_, err = s.FindByPaymentID(ctx, "")
+	assert.ErrorIs(t, err, ports.ErrInvalidQuery)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_SaveOrder_BusinessRuleViolation(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+
+	order := testmock.Test_order
+	order.Payment.Amount++
+	event, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	var handlerErr error
+	service := &Service{
+		repo:      mockRepo,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, order.OrderUID, event, &handlerErr),
+		validator: validator.New(),
+		rules:     order_entity.DefaultRuleValidator(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassValidation, msgErr.Class)
+	assert.Equal(t, []string{order_entity.RulePaymentAmount}, order_entity.ViolatedRules(handlerErr))
+	mockRepo.AssertNotCalled(t, "SaveOrder", mock.Anything, mock.Anything)
+}
+
+func TestService_SaveOrder_InvalidTransition(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	uid := testmock.Test_order.OrderUID
+
+	var handlerErr error
+	event := []byte(`{"type":"order.status_changed","version":1,"order_uid":"` + uid + `","status":"delivered"}`)
+	mockRepo.On("UpdateOrderStatus", mock.Anything, uid, "delivered").
+		Return(fmt.Errorf("order %s: %w: created -> delivered", uid, order_entity.ErrInvalidTransition))
+
+	service := &Service{
+		repo:      mockRepo,
+		cache:     mockCache,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, uid, event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassTransition, msgErr.Class)
+	mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestService_SaveOrder_UnknownStatus(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	uid := testmock.Test_order.OrderUID
+
+	var handlerErr error
+	event := []byte(`{"type":"order.status_changed","version":1,"order_uid":"` + uid + `","status":"lost"}`)
+	service := &Service{
+		repo:      mockRepo,
+		logger:    &testmock.TestLogger{},
+		consumer:  consumeOne(ctx, uid, event, &handlerErr),
+		validator: validator.New(),
+	}
+
+	require.NoError(t, service.SaveOrder(ctx))
+	var msgErr *ports.MessageError
+	require.ErrorAs(t, handlerErr, &msgErr)
+	assert.Equal(t, ports.ErrorClassValidation, msgErr.Class)
+	assert.ErrorIs(t, handlerErr, order_entity.ErrUnknownStatus)
+	mockRepo.AssertNotCalled(t, "UpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_OrderTimeline(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	s := &Service{repo: mockRepo, logger: &testmock.TestLogger{}}
+
+	timeline := []order_entity.StatusChange{{To: order_entity.StatusCreated}}
+	mockRepo.On("OrderTimeline", ctx, testmock.Test_order.OrderUID).Return(timeline, nil)
+
+	got, err := s.OrderTimeline(ctx, testmock.Test_order.OrderUID)
+	require.NoError(t, err)
+	assert.Equal(t, timeline, got)
+
+	_, err = s.OrderTimeline(ctx, "short")
+	assert.ErrorIs(t, err, ports.ErrInvalidOrderID)
+	mockRepo.AssertNumberOfCalls(t, "OrderTimeline", 1)
+}
+
+func TestService_GetOrderAsOf(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(testmock.MockRepository)
+	mockCache := new(testmock.MockCache)
+	s := &Service{repo: mockRepo, cache: mockCache, logger: &testmock.TestLogger{}}
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockRepo.On("GetOrderAsOf", ctx, testmock.Test_order.OrderUID, at).Return(testmock.Test_order, nil)
+
+	order, err := s.GetOrderAsOf(ctx, testmock.Test_order.OrderUID, at)
+	require.NoError(t, err)
+	assert.Equal(t, testmock.Test_order, order)
+	mockCache.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+
+	_, err = s.GetOrderAsOf(ctx, "short", at)
+	assert.ErrorIs(t, err, ports.ErrInvalidOrderID)
+}
+
+func TestService_CacheRestored(t *testing.T) {
+	s := &Service{logger: &testmock.TestLogger{}, warmup: WarmupPolicy{Mode: WarmupNone}}
+	ctx := context.Background()
+
+	assert.Error(t, s.CacheRestored(ctx))
+	require.NoError(t, s.Start(ctx))
+	assert.NoError(t, s.CacheRestored(ctx))
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// WarmupMode selects which orders Start loads into the cache.
+type WarmupMode string
+
+const (
+	WarmupAll    WarmupMode = "all"
+	WarmupRecent WarmupMode = "recent"
+	WarmupSince  WarmupMode = "since"
+	WarmupNone   WarmupMode = "none"
+)
+
+func ParseWarmupMode(s string) (WarmupMode, error) {
+	switch m := WarmupMode(s); m {
+	case WarmupAll, WarmupRecent, WarmupSince, WarmupNone:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown cache warm-up mode %q", s)
+}
+
+// WarmupPolicy bounds the cache warm-up: Limit is used by WarmupRecent and
+// Since by WarmupSince. Orders left out are still served from Postgres on a
+// cache miss.
+type WarmupPolicy struct {
+	Mode  WarmupMode
+	Limit int
+	Since time.Duration
+}
+
+// Validate rejects a policy that doesn't bound its mode. A recent warm-up
+// without a positive limit would load the whole table.
+func (p WarmupPolicy) Validate() error {
+	switch p.Mode {
+	case WarmupRecent:
+		if p.Limit <= 0 {
+			return fmt.Errorf("cache warm-up mode %q needs a positive limit, got %d", p.Mode, p.Limit)
+		}
+	case WarmupSince:
+		if p.Since <= 0 {
+			return fmt.Errorf("cache warm-up mode %q needs a positive duration, got %s", p.Mode, p.Since)
+		}
+	}
+	return nil
+}
+
+func DefaultWarmupPolicy() WarmupPolicy {
+	return WarmupPolicy{Mode: WarmupRecent, Limit: 10000, Since: 7 * 24 * time.Hour}
+}
+
+func WithWarmupPolicy(policy WarmupPolicy) Option {
+	return func(s *Service) {
+		s.warmup = policy
+	}
+}
@@ -8,4 +8,18 @@ import (
 type Cache interface {
 	Set(ctx context.Context, order order_entity.Order) error
 	Get(ctx context.Context, orderUID string) (order_entity.Order, bool, error)
+	Delete(ctx context.Context, orderUID string) error
+}
+
+// CustomerPageCache keeps pages of customers' order histories. All pages of a
+// customer are dropped together, since one new order shifts every page.
+//
+// Every invalidation bumps the customer's generation. SetPage stores a page
+// only while the generation it was loaded at is current, so a page read
+// before a concurrent write can't be cached after that write's invalidation.
+type CustomerPageCache interface {
+	Generation(ctx context.Context, customerID string) (int64, error)
+	GetPage(ctx context.Context, customerID, pageKey string) (CustomerOrdersPage, bool, error)
+	SetPage(ctx context.Context, customerID string, generation int64, pageKey string, page CustomerOrdersPage) error
+	InvalidateCustomer(ctx context.Context, customerID string) error
 }
@@ -14,5 +14,33 @@ type Consumer interface {
 }
 
 type Producer interface {
-	Send(key string, message []byte) error
+	Send(ctx context.Context, key string, message []byte) error
+}
+
+const (
+	ErrorClassUnmarshal  = "unmarshal"
+	ErrorClassValidation = "validation"
+	ErrorClassPersist    = "persist"
+	ErrorClassTransient  = "transient"
+	ErrorClassConflict   = "conflict"
+	ErrorClassNotFound   = "not_found"
+	// ErrorClassTransition is a status change the order's current status
+	// doesn't allow.
+	ErrorClassTransition = "invalid_transition"
+	ErrorClassUnknown    = "unknown"
+)
+
+// MessageError is returned by a Consumer handler for a message it gave up on.
+// Class ends up in the dead-letter headers so rejected messages can be triaged.
+type MessageError struct {
+	Class string
+	Err   error
+}
+
+func (e *MessageError) Error() string {
+	return e.Class + ": " + e.Err.Error()
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
 }
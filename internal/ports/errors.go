@@ -0,0 +1,11 @@
+package ports
+
+import "errors"
+
+// ErrTransient marks failures of a dependency (database, cache, network) that
+// may succeed when retried. Adapters wrap their errors with it.
+var ErrTransient = errors.New("transient failure")
+
+// ErrOrderConflict is returned when an order is stored again with a different
+// payload and the repository is configured to reject such changes.
+var ErrOrderConflict = errors.New("order already exists with different content")
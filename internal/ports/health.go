@@ -0,0 +1,10 @@
+package ports
+
+import "context"
+
+// HealthCheck probes one dependency for readiness. Check returns nil while
+// the dependency is usable.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
@@ -1,8 +1,40 @@
 package ports
 
+import "context"
+
+// Logger takes a message followed by alternating keys and values.
 type Logger interface {
 	Info(msg string, args ...interface{})
 	Error(msg string, args ...interface{})
 	Warn(msg string, args ...interface{})
 	Debug(msg string, args ...interface{})
+	// With returns a logger adding args to every record, e.g. a component
+	// name or the correlation fields of a request.
+	With(args ...interface{}) Logger
+}
+
+type logFieldsKey struct{}
+
+// ContextWithLogFields adds correlation fields, alternating keys and values
+// such as request_id or order_uid, to those already carried by ctx.
+func ContextWithLogFields(ctx context.Context, args ...interface{}) context.Context {
+	parent := LogFields(ctx)
+	// Copy, so contexts derived from the same parent don't share a backing array.
+	fields := make([]interface{}, 0, len(parent)+len(args))
+	fields = append(append(fields, parent...), args...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LogFields returns the correlation fields carried by ctx.
+func LogFields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return fields
+}
+
+// LoggerFromContext returns logger with the correlation fields of ctx.
+func LoggerFromContext(ctx context.Context, logger Logger) Logger {
+	if fields := LogFields(ctx); len(fields) > 0 {
+		return logger.With(fields...)
+	}
+	return logger
 }
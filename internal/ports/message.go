@@ -0,0 +1,25 @@
+package ports
+
+import "context"
+
+// MessageMetadata describes the broker record a Consumer handler is processing.
+type MessageMetadata struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	// HighWaterMark is the offset the next record produced to the partition
+	// will get, when the broker reported it.
+	HighWaterMark int64
+}
+
+type messageMetadataKey struct{}
+
+func ContextWithMessage(ctx context.Context, md MessageMetadata) context.Context {
+	return context.WithValue(ctx, messageMetadataKey{}, md)
+}
+
+func MessageFromContext(ctx context.Context) (MessageMetadata, bool) {
+	md, ok := ctx.Value(messageMetadataKey{}).(MessageMetadata)
+	return md, ok
+}
@@ -0,0 +1,25 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxMessage is an order event stored in the same transaction as the change
+// it describes, waiting to be published.
+type OutboxMessage struct {
+	ID        int64
+	OrderUID  string
+	EventType string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+type OutboxRepository interface {
+	// RelayOutbox passes up to limit pending messages, oldest first, to publish
+	// and marks the ones it accepted as published. publish runs outside any
+	// transaction. Once publish fails for an
+	// order_uid, later messages of that order are left pending to keep their order.
+	RelayOutbox(ctx context.Context, limit int, publish func(OutboxMessage) error) (int, error)
+	PurgeOutbox(ctx context.Context, publishedBefore time.Time) (int64, error)
+}
@@ -0,0 +1,87 @@
+package ports
+
+import (
+	"time"
+
+	order_entity "testberry/internal/domain/order"
+)
+
+// OrderSort orders a listing by date_created, newest first by default; ties
+// are broken by order_uid.
+type OrderSort string
+
+const (
+	SortCreatedDesc OrderSort = "-date_created"
+	SortCreatedAsc  OrderSort = "date_created"
+)
+
+// OrderFilter narrows a listing. Zero fields don't filter. ItemBrand and
+// ItemNmID match orders with at least one item having both.
+type OrderFilter struct {
+	CustomerID      string
+	TrackNumber     string
+	DeliveryService string
+	PaymentProvider string
+	PaymentBank     string
+	ItemBrand       string
+	ItemNmID        int
+	CreatedFrom     time.Time
+	CreatedTo       time.Time
+}
+
+// OrderQuery asks for one page of orders. Cursor is the NextCursor of the
+// previous page and must be used with the same Sort.
+type OrderQuery struct {
+	Filter OrderFilter
+	Sort   OrderSort
+	Limit  int
+	Cursor string
+}
+
+// OrderPage is a page of orders. NextCursor is empty on the last page.
+type OrderPage struct {
+	Orders     []order_entity.Order `json:"orders"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// CustomerOrdersQuery asks for one page of a customer's orders, newest first.
+// Cursor is the NextCursor of the previous page.
+type CustomerOrdersQuery struct {
+	CustomerID string
+	Limit      int
+	Cursor     string
+}
+
+// OrderSummary is the line shown for an order in a customer's history.
+// TotalAmount is the payment amount, in the minor units of Currency.
+type OrderSummary struct {
+	OrderUID     string    `json:"order_uid"`
+	DateCreated  time.Time `json:"date_created"`
+	TotalAmount  int       `json:"total_amount"`
+	Currency     string    `json:"currency"`
+	ItemCount    int       `json:"item_count"`
+	DeliveryCity string    `json:"delivery_city"`
+}
+
+// CustomerOrdersPage is a page of order summaries. NextCursor is empty on the
+// last page.
+type CustomerOrdersPage struct {
+	Orders     []OrderSummary `json:"orders"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// Places a lookup can match an order by, reported in OrderMatch.MatchedBy.
+const (
+	MatchOrderTrackNumber   = "orders.track_number"
+	MatchItemTrackNumber    = "item.track_number"
+	MatchPaymentTransaction = "payment.transaction"
+	MatchPaymentRequestID   = "payment.request_id"
+)
+
+// OrderMatch is an order found by a track number or payment ID, and the
+// fields that matched.
+type OrderMatch struct {
+	OrderUID    string    `json:"order_uid"`
+	DateCreated time.Time `json:"date_created"`
+	MatchedBy   []string  `json:"matched_by"`
+}
@@ -3,10 +3,38 @@ package ports
 import (
 	"context"
 	order_entity "testberry/internal/domain/order"
+	"time"
 )
 
+// StreamOptions bounds StreamOrders. A zero Limit or Since means no bound.
+type StreamOptions struct {
+	BatchSize int
+	Limit     int
+	Since     time.Time
+}
+
 type Repository interface {
 	SaveOrder(ctx context.Context, order order_entity.Order) error
+	// UpdateOrder returns the customer the order belonged to before the update.
+	UpdateOrder(ctx context.Context, order order_entity.Order) (previousCustomerID string, err error)
+	// UpdateOrderStatus and CancelOrder apply a status transition allowed by
+	// order_entity.CheckTransition and record it in the order's timeline.
+	UpdateOrderStatus(ctx context.Context, orderUID string, status string) error
+	CancelOrder(ctx context.Context, orderUID string) error
+	OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error)
+	// DeleteOrder returns the customer of the deleted order, empty when there
+	// was no such order.
+	DeleteOrder(ctx context.Context, orderUID string) (customerID string, err error)
 	GetOrderByID(ctx context.Context, orderUID string) (order_entity.Order, error)
-	RestoreCache(ctx context.Context) ([]order_entity.Order, error)
+	// GetOrderAsOf returns the order as the last write at or before at left it.
+	GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error)
+	ListOrders(ctx context.Context, query OrderQuery) (OrderPage, error)
+	ListCustomerOrders(ctx context.Context, query CustomerOrdersQuery) (CustomerOrdersPage, error)
+	// FindByTrackNumber and FindByPaymentID return at most limit matching
+	// orders, newest first.
+	FindByTrackNumber(ctx context.Context, trackNumber string, limit int) ([]OrderMatch, error)
+	FindByPaymentID(ctx context.Context, paymentID string, limit int) ([]OrderMatch, error)
+	// StreamOrders hands stored orders, newest first, to fn in batches and
+	// stops at the first error fn returns.
+	StreamOrders(ctx context.Context, opts StreamOptions, fn func(batch []order_entity.Order) error) error
 }
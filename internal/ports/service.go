@@ -2,9 +2,35 @@ package ports
 
 import (
 	"context"
+	"errors"
 	order_entity "testberry/internal/domain/order"
+	"time"
 )
 
+// Errors returned by OrderService and the adapters behind it, wrapped with
+// details. Callers match them with errors.Is.
+var (
+	ErrOrderNotFound  = errors.New("order not found")
+	ErrInvalidOrderID = errors.New("invalid order id")
+	ErrInvalidQuery   = errors.New("invalid order query")
+	// ErrDependencyUnavailable and ErrTimeout come wrapped together with
+	// ErrTransient: the database or cache failed or did not answer in time.
+	ErrDependencyUnavailable = errors.New("dependency unavailable")
+	ErrTimeout               = errors.New("dependency timed out")
+)
+
+// OrderUIDLength is the length of a valid order_uid.
+const OrderUIDLength = 20
+
 type OrderService interface {
 	GetOrder(ctx context.Context, orderUID string) (order_entity.Order, error)
+	GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error)
+	// OrderTimeline returns the order's status changes, oldest first.
+	OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error)
+	ListOrders(ctx context.Context, query OrderQuery) (OrderPage, error)
+	CustomerOrders(ctx context.Context, query CustomerOrdersQuery) (CustomerOrdersPage, error)
+	// FindByTrackNumber matches orders.track_number and item.track_number.
+	FindByTrackNumber(ctx context.Context, trackNumber string) ([]OrderMatch, error)
+	// FindByPaymentID matches payment.transaction and payment.request_id.
+	FindByPaymentID(ctx context.Context, paymentID string) ([]OrderMatch, error)
 }
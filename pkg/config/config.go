@@ -10,12 +10,15 @@ import (
 
 type Config struct {
 	DB struct {
-		Host     string
-		Port     string
-		User     string
-		Password string
-		Name     string
-		SSLMode  string
+		Host           string
+		Port           string
+		User           string
+		Password       string
+		Name           string
+		SSLMode        string
+		ConflictPolicy string   `env:"ORDER_CONFLICT_POLICY"`
+		DisabledRules  []string `env:"ORDER_RULES_DISABLED"`
+		MigrateOnStart bool     `env:"MIGRATE_ON_START"`
 	}
 	Redis struct {
 		Host         string        `env:"REDIS_HOST"`
@@ -28,10 +31,53 @@ type Config struct {
 		PoolSize     int           `env:"REDIS_POOL_SIZE"`
 		TLS          bool          `env:"REDIS_TLS"`
 	}
+	Cache struct {
+		Backend     string        `env:"CACHE_BACKEND"`
+		MaxEntries  int           `env:"CACHE_MAX_ENTRIES"`
+		MaxBytes    int64         `env:"CACHE_MAX_BYTES"`
+		TTL         time.Duration `env:"CACHE_TTL"`
+		LocalTTL    time.Duration `env:"CACHE_LOCAL_TTL"`
+		NegativeTTL time.Duration `env:"CACHE_NEGATIVE_TTL"`
+		PageTTL     time.Duration `env:"CACHE_CUSTOMER_PAGE_TTL"`
+		Channel     string        `env:"CACHE_INVALIDATION_CHANNEL"`
+		WarmupMode  string        `env:"CACHE_WARMUP_MODE"`
+		WarmupLimit int           `env:"CACHE_WARMUP_LIMIT"`
+		WarmupSince time.Duration `env:"CACHE_WARMUP_SINCE"`
+	}
 	Kafka struct {
-		Brokers       []string `env:"KAFKA_BROKERS"`
-		Topic         string   `env:"KAFKA_TOPIC"`
-		ConsumerGroup string   `env:"KAFKA_CONSUMER_GROUP"`
+		Brokers         []string `env:"KAFKA_BROKERS"`
+		Topic           string   `env:"KAFKA_TOPIC"`
+		ConsumerGroup   string   `env:"KAFKA_CONSUMER_GROUP"`
+		DeadLetterTopic string   `env:"KAFKA_DLQ_TOPIC"`
+		OutboxTopic     string   `env:"KAFKA_OUTBOX_TOPIC"`
+	}
+	Outbox struct {
+		PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL"`
+		BatchSize    int           `env:"OUTBOX_BATCH_SIZE"`
+		Retention    time.Duration `env:"OUTBOX_RETENTION"`
+	}
+	Tracing struct {
+		Exporter    string `env:"OTEL_TRACES_EXPORTER"`
+		ServiceName string `env:"OTEL_SERVICE_NAME"`
+		Endpoint    string `env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	}
+	Log struct {
+		Format string `env:"LOG_FORMAT"`
+		Level  string `env:"LOG_LEVEL"`
+	}
+	PII struct {
+		Policies     string   `env:"PII_POLICIES"`
+		HashKey      string   `env:"PII_HASH_KEY"`
+		ReaderTokens []string `env:"PII_READER_TOKENS"`
+	}
+	Health struct {
+		CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT"`
+	}
+	Retry struct {
+		MaxAttempts     int           `env:"RETRY_MAX_ATTEMPTS"`
+		InitialInterval time.Duration `env:"RETRY_INITIAL_INTERVAL"`
+		MaxInterval     time.Duration `env:"RETRY_MAX_INTERVAL"`
+		MaxElapsed      time.Duration `env:"RETRY_MAX_ELAPSED"`
 	}
 }
 
@@ -44,6 +90,9 @@ func LoadConfig() *Config {
 	cfg.DB.Password = getEnv("DB_PASSWORD")
 	cfg.DB.Name = getEnv("DB_NAME")
 	cfg.DB.SSLMode = getEnv("DB_SSLMODE")
+	cfg.DB.ConflictPolicy = getEnvWithDefault("ORDER_CONFLICT_POLICY", "reject")
+	cfg.DB.DisabledRules = mustParseStringSlice("ORDER_RULES_DISABLED", nil)
+	cfg.DB.MigrateOnStart = mustParseBool("MIGRATE_ON_START", false)
 
 	cfg.Redis.Host = getEnv("REDIS_HOST")
 	cfg.Redis.Port = mustAtoi("REDIS_PORT", 6379)
@@ -55,9 +104,45 @@ func LoadConfig() *Config {
 	cfg.Redis.PoolSize = mustAtoi("REDIS_POOL_SIZE", 10)
 	cfg.Redis.TLS = mustParseBool("REDIS_TLS", false)
 
+	cfg.Cache.Backend = getEnvWithDefault("CACHE_BACKEND", "redis")
+	cfg.Cache.MaxEntries = mustAtoi("CACHE_MAX_ENTRIES", 100000)
+	cfg.Cache.MaxBytes = int64(mustAtoi("CACHE_MAX_BYTES", 256<<20))
+	cfg.Cache.TTL = mustParseDuration("CACHE_TTL", 24*time.Hour)
+	cfg.Cache.LocalTTL = mustParseDuration("CACHE_LOCAL_TTL", time.Minute)
+	cfg.Cache.NegativeTTL = mustParseDuration("CACHE_NEGATIVE_TTL", 0)
+	cfg.Cache.PageTTL = mustParseDuration("CACHE_CUSTOMER_PAGE_TTL", 5*time.Minute)
+	cfg.Cache.Channel = getEnvWithDefault("CACHE_INVALIDATION_CHANNEL", "orders:invalidate")
+	cfg.Cache.WarmupMode = getEnvWithDefault("CACHE_WARMUP_MODE", "recent")
+	cfg.Cache.WarmupLimit = mustAtoi("CACHE_WARMUP_LIMIT", 10000)
+	cfg.Cache.WarmupSince = mustParseDuration("CACHE_WARMUP_SINCE", 7*24*time.Hour)
+
 	cfg.Kafka.Brokers = mustParseStringSlice("KAFKA_BROKERS", []string{"localhost:9092"})
 	cfg.Kafka.Topic = getEnvWithDefault("KAFKA_TOPIC", "orders")
 	cfg.Kafka.ConsumerGroup = getEnvWithDefault("KAFKA_CONSUMER_GROUP", "my-consumer-group")
+	cfg.Kafka.DeadLetterTopic = getEnvWithDefault("KAFKA_DLQ_TOPIC", "orders.dlq")
+	cfg.Kafka.OutboxTopic = getEnvWithDefault("KAFKA_OUTBOX_TOPIC", "orders.events")
+
+	cfg.Outbox.PollInterval = mustParseDuration("OUTBOX_POLL_INTERVAL", time.Second)
+	cfg.Outbox.BatchSize = mustAtoi("OUTBOX_BATCH_SIZE", 100)
+	cfg.Outbox.Retention = mustParseDuration("OUTBOX_RETENTION", 24*time.Hour)
+
+	cfg.Tracing.Exporter = getEnvWithDefault("OTEL_TRACES_EXPORTER", "none")
+	cfg.Tracing.ServiceName = getEnvWithDefault("OTEL_SERVICE_NAME", "order-service")
+	cfg.Tracing.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+
+	cfg.Log.Format = getEnvWithDefault("LOG_FORMAT", "json")
+	cfg.Log.Level = getEnvWithDefault("LOG_LEVEL", "info")
+
+	cfg.PII.Policies = os.Getenv("PII_POLICIES")
+	cfg.PII.HashKey = os.Getenv("PII_HASH_KEY")
+	cfg.PII.ReaderTokens = mustParseStringSlice("PII_READER_TOKENS", nil)
+
+	cfg.Health.CheckTimeout = mustParseDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second)
+
+	cfg.Retry.MaxAttempts = mustAtoi("RETRY_MAX_ATTEMPTS", 5)
+	cfg.Retry.InitialInterval = mustParseDuration("RETRY_INITIAL_INTERVAL", 200*time.Millisecond)
+	cfg.Retry.MaxInterval = mustParseDuration("RETRY_MAX_INTERVAL", 5*time.Second)
+	cfg.Retry.MaxElapsed = mustParseDuration("RETRY_MAX_ELAPSED", 30*time.Second)
 
 	return cfg
 }
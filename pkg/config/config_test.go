@@ -192,7 +192,43 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	if cfg.Kafka.ConsumerGroup != "my-consumer-group" {
 		t.Errorf("Expected default Kafka group, got %s", cfg.Kafka.ConsumerGroup)
 	}
+	if cfg.Kafka.DeadLetterTopic != "orders.dlq" {
+		t.Errorf("Expected default Kafka dead-letter topic 'orders.dlq', got %s", cfg.Kafka.DeadLetterTopic)
+	}
 	if cfg.Redis.DialTimeout != 5*time.Second {
 		t.Errorf("Expected default DialTimeout 5s, got %v", cfg.Redis.DialTimeout)
 	}
+	if cfg.DB.ConflictPolicy != "reject" {
+		t.Errorf("Expected default order conflict policy 'reject', got %s", cfg.DB.ConflictPolicy)
+	}
+	if len(cfg.DB.DisabledRules) != 0 {
+		t.Errorf("Expected every business rule enabled by default, got %v disabled", cfg.DB.DisabledRules)
+	}
+	if cfg.Retry.MaxAttempts != 5 {
+		t.Errorf("Expected default retry max attempts 5, got %d", cfg.Retry.MaxAttempts)
+	}
+	if cfg.Retry.MaxElapsed != 30*time.Second {
+		t.Errorf("Expected default retry max elapsed 30s, got %v", cfg.Retry.MaxElapsed)
+	}
+	if cfg.Cache.Backend != "redis" {
+		t.Errorf("Expected default cache backend 'redis', got %s", cfg.Cache.Backend)
+	}
+	if cfg.Cache.TTL != 24*time.Hour {
+		t.Errorf("Expected default cache TTL 24h, got %v", cfg.Cache.TTL)
+	}
+	if cfg.Cache.PageTTL != 5*time.Minute {
+		t.Errorf("Expected default customer page TTL 5m, got %v", cfg.Cache.PageTTL)
+	}
+	if cfg.Cache.WarmupMode != "recent" || cfg.Cache.WarmupLimit != 10000 {
+		t.Errorf("Expected default warm-up of the 10000 most recent orders, got %s/%d", cfg.Cache.WarmupMode, cfg.Cache.WarmupLimit)
+	}
+	if cfg.Kafka.OutboxTopic != "orders.events" {
+		t.Errorf("Expected default Kafka outbox topic 'orders.events', got %s", cfg.Kafka.OutboxTopic)
+	}
+	if cfg.Outbox.BatchSize != 100 {
+		t.Errorf("Expected default outbox batch size 100, got %d", cfg.Outbox.BatchSize)
+	}
+	if cfg.Outbox.Retention != 24*time.Hour {
+		t.Errorf("Expected default outbox retention 24h, got %v", cfg.Outbox.Retention)
+	}
 }
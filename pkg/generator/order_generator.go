@@ -60,7 +60,7 @@ func GenerateRandomOrder(seed int64) order_entity.Order {
 				TotalPrice:  317,
 				NmID:        2389212,
 				Brand:       "Vivienne Sabo",
-				Status:      202,
+				Status:      order_entity.ItemStatusCreated,
 			},
 		},
 		Locale:            "en",
@@ -1,21 +1,68 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
-	"os"
+	"strings"
+	order_entity "testberry/internal/domain/order"
 	"testberry/internal/ports"
+	"testberry/pkg/redact"
 )
 
 type SlogAdapter struct {
 	logger *slog.Logger
 }
 
-func NewSlogAdapter() ports.Logger {
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	})
-	slogger := slog.New(handler)
-	return &SlogAdapter{logger: slogger}
+type Option func(*slog.HandlerOptions)
+
+// WithRedactor masks personal data before it is written: attributes named
+// after a covered field, such as "phone", and orders or deliveries logged
+// whole.
+func WithRedactor(r *redact.Redactor) Option {
+	return func(opts *slog.HandlerOptions) {
+		opts.ReplaceAttr = func(_ []string, a slog.Attr) slog.Attr {
+			switch v := a.Value.Any().(type) {
+			case string:
+				if r.Covers(a.Key) {
+					a.Value = slog.StringValue(r.Mask(a.Key, v))
+				}
+			case order_entity.Order:
+				a.Value = slog.AnyValue(v.MaskPII(r.Mask))
+			case *order_entity.Order:
+				if v != nil {
+					a.Value = slog.AnyValue(v.MaskPII(r.Mask))
+				}
+			case order_entity.Delivery:
+				a.Value = slog.AnyValue(v.MaskPII(r.Mask))
+			}
+			return a
+		}
+	}
+}
+
+// New returns a logger writing records of at least level ("debug", "info",
+// "warn" or "error") to w, as JSON or as text.
+func New(w io.Writer, format, level string, options ...Option) (ports.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	for _, option := range options {
+		option(opts)
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected json or text", format)
+	}
+	return &SlogAdapter{logger: slog.New(handler)}, nil
 }
 
 func (l *SlogAdapter) Info(msg string, args ...interface{}) {
@@ -33,3 +80,7 @@ func (l *SlogAdapter) Warn(msg string, args ...interface{}) {
 func (l *SlogAdapter) Debug(msg string, args ...interface{}) {
 	l.logger.Debug(msg, args...)
 }
+
+func (l *SlogAdapter) With(args ...interface{}) ports.Logger {
+	return &SlogAdapter{logger: l.logger.With(args...)}
+}
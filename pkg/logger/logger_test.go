@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
+	"testberry/pkg/redact"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_JSONWithCorrelationFields(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, "json", "info")
+	require.NoError(t, err)
+
+	ctx := ports.ContextWithLogFields(context.Background(), "request_id", "req-1")
+	ctx = ports.ContextWithLogFields(ctx, "order_uid", "b563feb7b2b84b6test")
+	ports.LoggerFromContext(ctx, log.With("component", "http")).Info("Order served", "status", 200)
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "Order served", record["msg"])
+	assert.Equal(t, "http", record["component"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "b563feb7b2b84b6test", record["order_uid"])
+	assert.Equal(t, float64(200), record["status"])
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, "text", "WARN")
+	require.NoError(t, err)
+
+	log.Debug("debug")
+	log.Info("info")
+	log.Warn("warn")
+	log.Error("error")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "level=WARN")
+	assert.Contains(t, lines[1], "level=ERROR")
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "xml", "info")
+	assert.Error(t, err)
+
+	_, err = New(&bytes.Buffer{}, "json", "verbose")
+	assert.Error(t, err)
+}
+
+func TestNew_WithRedactor(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, "json", "info", WithRedactor(redact.New(redact.DefaultPolicies(), nil)))
+	require.NoError(t, err)
+
+	delivery := order_entity.Delivery{Name: "Test User", Phone: "+79001234567", City: "Kiryat Mozkin", Email: "test@gmail.com"}
+	log.Info("Order received", "phone", "+79001234567", "delivery", delivery, "migration", "schema")
+
+	out := buf.String()
+	for _, pii := range []string{"Test User", "+79001234567", "test@gmail.com"} {
+		assert.NotContains(t, out, pii)
+	}
+	assert.Contains(t, out, `"phone":"+790*****67"`)
+	assert.Contains(t, out, "Kiryat Mozkin")
+	assert.Contains(t, out, `"migration":"schema"`)
+}
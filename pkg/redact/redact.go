@@ -0,0 +1,128 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Policy is how the value of a personal data field is masked.
+type Policy string
+
+const (
+	// None leaves the value as is.
+	None Policy = "none"
+	// Full replaces the value entirely.
+	Full Policy = "full"
+	// Partial keeps a few leading and trailing characters, e.g. +972*****00,
+	// and the domain of an email.
+	Partial Policy = "partial"
+	// Hash replaces the value with a keyed hash, so equal values can still be
+	// matched without being revealed.
+	Hash Policy = "hash"
+)
+
+const mask = "*****"
+
+// DefaultPolicies masks every delivery field but the city and region, which
+// order summaries and analytics rely on.
+func DefaultPolicies() map[string]Policy {
+	return map[string]Policy{
+		"name":    Partial,
+		"phone":   Partial,
+		"email":   Partial,
+		"address": Full,
+		"zip":     Full,
+		"city":    None,
+		"region":  None,
+	}
+}
+
+// ParsePolicies parses "field=policy" pairs separated by commas, such as
+// "phone=partial,email=hash". The result overrides the defaults.
+func ParsePolicies(s string) (map[string]Policy, error) {
+	policies := DefaultPolicies()
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		field, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid policy %q, expected field=policy", pair)
+		}
+		policy := Policy(strings.ToLower(strings.TrimSpace(value)))
+		switch policy {
+		case None, Full, Partial, Hash:
+		default:
+			return nil, fmt.Errorf("unknown policy %q for %s, expected none, full, partial or hash", value, field)
+		}
+		policies[strings.ToLower(strings.TrimSpace(field))] = policy
+	}
+	return policies, nil
+}
+
+// Redactor masks personal data fields by their policies. Fields without a
+// policy are left as is.
+type Redactor struct {
+	policies map[string]Policy
+	key      []byte
+}
+
+// New returns a Redactor. Hashes are HMAC-SHA256 under key, so they can't be
+// reversed by hashing every phone number; an empty key falls back to plain
+// SHA-256.
+func New(policies map[string]Policy, key []byte) *Redactor {
+	return &Redactor{policies: policies, key: key}
+}
+
+// Covers reports whether field has a policy other than None.
+func (r *Redactor) Covers(field string) bool {
+	policy, ok := r.policies[field]
+	return ok && policy != None
+}
+
+// Mask returns value masked by the policy of field. Empty values stay empty.
+func (r *Redactor) Mask(field, value string) string {
+	if value == "" {
+		return value
+	}
+	switch r.policies[field] {
+	case Full:
+		return mask
+	case Partial:
+		return partial(value)
+	case Hash:
+		return r.hash(value)
+	default:
+		return value
+	}
+}
+
+func (r *Redactor) hash(value string) string {
+	var sum []byte
+	if len(r.key) > 0 {
+		h := hmac.New(sha256.New, r.key)
+		h.Write([]byte(value))
+		sum = h.Sum(nil)
+	} else {
+		s := sha256.Sum256([]byte(value))
+		sum = s[:]
+	}
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// partial keeps up to a third of the value at the start and a quarter at the
+// end, at most 4 and 2 characters. The mask has a fixed width, so it doesn't
+// give away the length. Emails keep their domain.
+func partial(value string) string {
+	if local, domain, ok := strings.Cut(value, "@"); ok {
+		return partial(local) + "@" + domain
+	}
+	runes := []rune(value)
+	prefix := min(4, len(runes)/3)
+	suffix := min(2, len(runes)/4)
+	return string(runes[:prefix]) + mask + string(runes[len(runes)-suffix:])
+}
@@ -0,0 +1,47 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Mask(t *testing.T) {
+	r := New(map[string]Policy{
+		"name":    Partial,
+		"phone":   Partial,
+		"email":   Partial,
+		"address": Full,
+		"zip":     Hash,
+		"city":    None,
+	}, []byte("key"))
+
+	assert.Equal(t, "+972*****00", r.Mask("phone", "+972540000000"))
+	assert.Equal(t, "Tes*****er", r.Mask("name", "Test User"))
+	assert.Equal(t, "t*****t@gmail.com", r.Mask("email", "test@gmail.com"))
+	assert.Equal(t, "*****", r.Mask("name", "Al"))
+	assert.Equal(t, "*****", r.Mask("address", "Ploshad Mira 15"))
+	assert.Equal(t, "Kiryat Mozkin", r.Mask("city", "Kiryat Mozkin"))
+	assert.Equal(t, "WBIL", r.Mask("entry", "WBIL"), "fields without a policy are left as is")
+	assert.Empty(t, r.Mask("phone", ""))
+
+	hash := r.Mask("zip", "2639809")
+	assert.True(t, strings.HasPrefix(hash, "sha256:"))
+	assert.Equal(t, hash, r.Mask("zip", "2639809"), "hashes are stable")
+	assert.NotEqual(t, hash, New(map[string]Policy{"zip": Hash}, []byte("other")).Mask("zip", "2639809"), "hashes depend on the key")
+}
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(" phone=hash, City=Full ,")
+	require.NoError(t, err)
+	assert.Equal(t, Hash, policies["phone"])
+	assert.Equal(t, Full, policies["city"])
+	assert.Equal(t, Partial, policies["name"], "defaults are kept")
+
+	_, err = ParsePolicies("phone")
+	assert.Error(t, err)
+	_, err = ParsePolicies("phone=scramble")
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+type Policy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter is the randomization factor applied to every delay, from 0 to 1.
+	Jitter float64
+	// MaxAttempts counts the first call too; values below 1 mean a single attempt.
+	MaxAttempts int
+	// MaxElapsed stops retrying once the next delay would exceed it. Zero disables the limit.
+	MaxElapsed time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		InitialInterval: 200 * time.Millisecond,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxAttempts:     5,
+		MaxElapsed:      30 * time.Second,
+	}
+}
+
+// Delay returns the un-jittered backoff before the given retry (1-based).
+func (p Policy) Delay(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialInterval) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+	return time.Duration(delay)
+}
+
+func (p Policy) jittered(d time.Duration) time.Duration {
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	delta := p.Jitter * float64(d)
+	return time.Duration(float64(d) - delta + rand.Float64()*2*delta)
+}
+
+// Notify is called before every retry with the attempt that failed.
+type Notify func(attempt int, delay time.Duration, err error)
+
+// Do calls op until it succeeds, returns an error rejected by retryable, or the
+// policy budget is spent. It returns the number of attempts made and the last error.
+// Cancelling ctx stops waiting immediately.
+func Do(ctx context.Context, p Policy, retryable func(error) bool, notify Notify, op func(ctx context.Context) error) (int, error) {
+	start := time.Now()
+	attempt := 0
+	for {
+		attempt++
+		err := op(ctx)
+		if err == nil {
+			return attempt, nil
+		}
+		if ctx.Err() != nil {
+			return attempt, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		}
+		if !retryable(err) || attempt >= p.MaxAttempts {
+			return attempt, err
+		}
+
+		delay := p.jittered(p.Delay(attempt))
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return attempt, err
+		}
+		if notify != nil {
+			notify(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
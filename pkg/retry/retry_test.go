@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary")
+
+func isTemporary(err error) bool { return errors.Is(err, errTemporary) }
+
+func testPolicy() Policy {
+	return Policy{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     4 * time.Millisecond,
+		Multiplier:      2,
+		Jitter:          0.5,
+		MaxAttempts:     4,
+	}
+}
+
+func TestDo_SucceedsAfterRetries(t *testing.T) {
+	calls := 0
+	notified := 0
+	attempts, err := Do(context.Background(), testPolicy(), isTemporary,
+		func(attempt int, delay time.Duration, err error) { notified++ },
+		func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTemporary
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+	if attempts != 3 || calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d (calls %d)", attempts, calls)
+	}
+	if notified != 2 {
+		t.Errorf("Expected 2 retry notifications, got %d", notified)
+	}
+}
+
+func TestDo_PermanentErrorIsNotRetried(t *testing.T) {
+	permanent := errors.New("permanent")
+	attempts, err := Do(context.Background(), testPolicy(), isTemporary, nil, func(ctx context.Context) error {
+		return permanent
+	})
+	if !errors.Is(err, permanent) {
+		t.Fatalf("Expected permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestDo_MaxAttempts(t *testing.T) {
+	attempts, err := Do(context.Background(), testPolicy(), isTemporary, nil, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if !errors.Is(err, errTemporary) {
+		t.Fatalf("Expected temporary error, got %v", err)
+	}
+	if attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %d", attempts)
+	}
+}
+
+func TestDo_ZeroPolicyRunsOnce(t *testing.T) {
+	attempts, err := Do(context.Background(), Policy{}, isTemporary, nil, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected a single failed attempt, got %d, %v", attempts, err)
+	}
+}
+
+func TestDo_MaxElapsed(t *testing.T) {
+	p := testPolicy()
+	p.MaxAttempts = 100
+	p.InitialInterval = 20 * time.Millisecond
+	p.MaxInterval = 20 * time.Millisecond
+	p.Jitter = 0
+	p.MaxElapsed = 50 * time.Millisecond
+
+	attempts, err := Do(context.Background(), p, isTemporary, nil, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if !errors.Is(err, errTemporary) {
+		t.Fatalf("Expected temporary error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts within the elapsed budget, got %d", attempts)
+	}
+}
+
+func TestDo_StopsOnContextCancel(t *testing.T) {
+	p := testPolicy()
+	p.MaxAttempts = 100
+	p.InitialInterval = time.Hour
+	p.MaxInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := Do(ctx, p, isTemporary, nil, func(ctx context.Context) error {
+		return errTemporary
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Do to return promptly after cancel")
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	p := Policy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, want := range expected {
+		if got := p.Delay(i + 1); got != want {
+			t.Errorf("Delay(%d): expected %v, got %v", i+1, want, got)
+		}
+	}
+}
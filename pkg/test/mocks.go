@@ -3,6 +3,7 @@ package testmock
 import (
 	"context"
 	order_entity "testberry/internal/domain/order"
+	"testberry/internal/ports"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -17,6 +18,36 @@ func (m *MockOrderService) GetOrder(ctx context.Context, orderUID string) (order
 	return args.Get(0).(order_entity.Order), args.Error(1)
 }
 
+func (m *MockOrderService) GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error) {
+	args := m.Called(ctx, orderUID, at)
+	return args.Get(0).(order_entity.Order), args.Error(1)
+}
+
+func (m *MockOrderService) OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error) {
+	args := m.Called(ctx, orderUID)
+	return args.Get(0).([]order_entity.StatusChange), args.Error(1)
+}
+
+func (m *MockOrderService) ListOrders(ctx context.Context, query ports.OrderQuery) (ports.OrderPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(ports.OrderPage), args.Error(1)
+}
+
+func (m *MockOrderService) CustomerOrders(ctx context.Context, query ports.CustomerOrdersQuery) (ports.CustomerOrdersPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(ports.CustomerOrdersPage), args.Error(1)
+}
+
+func (m *MockOrderService) FindByTrackNumber(ctx context.Context, trackNumber string) ([]ports.OrderMatch, error) {
+	args := m.Called(ctx, trackNumber)
+	return args.Get(0).([]ports.OrderMatch), args.Error(1)
+}
+
+func (m *MockOrderService) FindByPaymentID(ctx context.Context, paymentID string) ([]ports.OrderMatch, error) {
+	args := m.Called(ctx, paymentID)
+	return args.Get(0).([]ports.OrderMatch), args.Error(1)
+}
+
 func (m *MockOrderService) SaveOrder(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -41,9 +72,47 @@ func (m *MockRepository) GetOrderByID(ctx context.Context, uid string) (order_en
 	return args.Get(0).(order_entity.Order), args.Error(1)
 }
 
-func (m *MockRepository) RestoreCache(ctx context.Context) ([]order_entity.Order, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]order_entity.Order), args.Error(1)
+// StreamOrders hands the orders returned for the call to fn in batches of opts.BatchSize.
+func (m *MockRepository) StreamOrders(ctx context.Context, opts ports.StreamOptions, fn func(batch []order_entity.Order) error) error {
+	args := m.Called(ctx, opts)
+	orders := args.Get(0).([]order_entity.Order)
+	for start := 0; start < len(orders); start += opts.BatchSize {
+		end := min(start+opts.BatchSize, len(orders))
+		if err := fn(orders[start:end]); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRepository) GetOrderAsOf(ctx context.Context, orderUID string, at time.Time) (order_entity.Order, error) {
+	args := m.Called(ctx, orderUID, at)
+	return args.Get(0).(order_entity.Order), args.Error(1)
+}
+
+func (m *MockRepository) OrderTimeline(ctx context.Context, orderUID string) ([]order_entity.StatusChange, error) {
+	args := m.Called(ctx, orderUID)
+	return args.Get(0).([]order_entity.StatusChange), args.Error(1)
+}
+
+func (m *MockRepository) ListOrders(ctx context.Context, query ports.OrderQuery) (ports.OrderPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(ports.OrderPage), args.Error(1)
+}
+
+func (m *MockRepository) ListCustomerOrders(ctx context.Context, query ports.CustomerOrdersQuery) (ports.CustomerOrdersPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(ports.CustomerOrdersPage), args.Error(1)
+}
+
+func (m *MockRepository) FindByTrackNumber(ctx context.Context, trackNumber string, limit int) ([]ports.OrderMatch, error) {
+	args := m.Called(ctx, trackNumber, limit)
+	return args.Get(0).([]ports.OrderMatch), args.Error(1)
+}
+
+func (m *MockRepository) FindByPaymentID(ctx context.Context, paymentID string, limit int) ([]ports.OrderMatch, error) {
+	args := m.Called(ctx, paymentID, limit)
+	return args.Get(0).([]ports.OrderMatch), args.Error(1)
 }
 
 func (m *MockRepository) SaveOrder(ctx context.Context, order order_entity.Order) error {
@@ -51,6 +120,26 @@ func (m *MockRepository) SaveOrder(ctx context.Context, order order_entity.Order
 	return args.Error(0)
 }
 
+func (m *MockRepository) UpdateOrder(ctx context.Context, order order_entity.Order) (string, error) {
+	args := m.Called(ctx, order)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) UpdateOrderStatus(ctx context.Context, uid string, status string) error {
+	args := m.Called(ctx, uid, status)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CancelOrder(ctx context.Context, uid string) error {
+	args := m.Called(ctx, uid)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteOrder(ctx context.Context, uid string) (string, error) {
+	args := m.Called(ctx, uid)
+	return args.String(0), args.Error(1)
+}
+
 type MockCache struct {
 	mock.Mock
 }
@@ -65,6 +154,66 @@ func (m *MockCache) Set(ctx context.Context, o order_entity.Order) error {
 	return args.Error(0)
 }
 
+func (m *MockCache) Delete(ctx context.Context, uid string) error {
+	args := m.Called(ctx, uid)
+	return args.Error(0)
+}
+
+type MockCustomerPageCache struct {
+	mock.Mock
+}
+
+func (m *MockCustomerPageCache) Generation(ctx context.Context, customerID string) (int64, error) {
+	args := m.Called(ctx, customerID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCustomerPageCache) GetPage(ctx context.Context, customerID, pageKey string) (ports.CustomerOrdersPage, bool, error) {
+	args := m.Called(ctx, customerID, pageKey)
+	return args.Get(0).(ports.CustomerOrdersPage), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCustomerPageCache) SetPage(ctx context.Context, customerID string, generation int64, pageKey string, page ports.CustomerOrdersPage) error {
+	args := m.Called(ctx, customerID, generation, pageKey, page)
+	return args.Error(0)
+}
+
+func (m *MockCustomerPageCache) InvalidateCustomer(ctx context.Context, customerID string) error {
+	args := m.Called(ctx, customerID)
+	return args.Error(0)
+}
+
+// MockOutboxRepository hands the messages returned for RelayOutbox to publish
+// and reports how many were accepted.
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) RelayOutbox(ctx context.Context, limit int, publish func(ports.OutboxMessage) error) (int, error) {
+	args := m.Called(ctx, limit)
+	published := 0
+	for _, msg := range args.Get(0).([]ports.OutboxMessage) {
+		if err := publish(msg); err == nil {
+			published++
+		}
+	}
+	return published, args.Error(1)
+}
+
+func (m *MockOutboxRepository) PurgeOutbox(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, publishedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockProducer struct {
+	mock.Mock
+}
+
+func (m *MockProducer) Send(ctx context.Context, key string, message []byte) error {
+	args := m.Called(ctx, key, message)
+	return args.Error(0)
+}
+
 type MockConsumer struct {
 	ConsumeFunc func(ctx context.Context, handler func(context.Context, []byte) error) error
 }
@@ -79,6 +228,7 @@ func (l *TestLogger) Info(msg string, keysAndValues ...interface{})  {}
 func (l *TestLogger) Error(msg string, keysAndValues ...interface{}) {}
 func (l *TestLogger) Warn(msg string, keysAndValues ...interface{})  {}
 func (l *TestLogger) Debug(msg string, keysAndValues ...interface{}) {}
+func (l *TestLogger) With(keysAndValues ...interface{}) ports.Logger { return l }
 
 var Test_order order_entity.Order = order_entity.Order{
 	OrderUID:        "12345678901234567890",
@@ -124,7 +274,7 @@ var Test_order order_entity.Order = order_entity.Order{
 			TotalPrice:  317,
 			NmID:        2389212,
 			Brand:       "Vivienne Sabo",
-			Status:      202,
+			Status:      order_entity.ItemStatusCreated,
 		},
 	},
 }